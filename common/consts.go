@@ -3,6 +3,7 @@ package common
 const (
 	Empty              = ""
 	Colon              = ":"
+	Comma              = ","
 	LeftSquareBracket  = "["
 	RightSquareBracket = "]"
 	SquareBrackets     = LeftSquareBracket + RightSquareBracket
@@ -2,6 +2,15 @@ module github.com/densify-dev/net-utils
 
 go 1.23
 
-require github.com/hashicorp/go-retryablehttp v0.7.7
+require (
+	github.com/hashicorp/go-retryablehttp v0.7.7
+	golang.org/x/net v0.35.0
+	golang.org/x/sync v0.11.0
+	golang.org/x/time v0.10.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
-require github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
+require (
+	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
+	golang.org/x/text v0.22.0 // indirect
+)
@@ -1,12 +1,70 @@
 package network
 
 import (
+	"errors"
 	"fmt"
-	"github.com/densify-dev/net-utils/common"
 	"net"
 	"strings"
 )
 
+// the separators of addresses and ports are defined here rather than imported, to keep the package self-contained
+const (
+	empty        = ""
+	colon        = ":"
+	comma        = ","
+	leftBracket  = "["
+	rightBracket = "]"
+	percent      = "%"
+	slash        = "/"
+	newline      = "\n"
+	mappedIPv4   = "::ffff:"
+)
+
+// ParseOption adds validations to ParseAddressWithOptions; options are combined with bitwise or
+type ParseOption uint
+
+const (
+	// StrictPort rejects a port which is not in canonical decimal form, e.g. "080" or " 80"
+	StrictPort ParseOption = 1 << iota
+	// StrictIP rejects an IP address which is not in canonical form: dotted decimal for IPv4,
+	// "::ffff:" followed by dotted decimal for IPv4-mapped IPv6, and RFC 5952 for any other IPv6
+	// address (lowercase hexadecimal, no leading zeros, longest run of zero groups compressed)
+	StrictIP
+	// NormalizeIPv4 returns an IPv4-mapped IPv6 address in its IPv4 dotted decimal form,
+	// e.g. "[::ffff:192.0.2.1]:80" yields the address component "192.0.2.1"; it is applied after
+	// StrictIP, i.e. the input must be canonical in the IPv4-mapped IPv6 form
+	NormalizeIPv4
+	// DefaultPort fills in the default port of the scheme of a URL without a port (see ParseURLAddressWithOptions)
+	DefaultPort
+	// WildcardPort accepts port 0, the wildcard port (see Port.IsWildcard), regardless of the port type
+	// range; it is meant for addresses to listen on, e.g. "127.0.0.1:0" with the NonSystem range
+	WildcardPort
+	// EmptyHost accepts an empty address component with a port, e.g. ":8080", which means all the
+	// interfaces when listening (see net.Listen), returning an empty address component (and a nil IP);
+	// empty square brackets, e.g. "[]:8080", are still invalid
+	EmptyHost
+	// EmptyHostIPv4 behaves like EmptyHost, only that it returns the IPv4 unspecified address "0.0.0.0"
+	EmptyHostIPv4
+	// EmptyHostIPv6 behaves like EmptyHost, only that it returns the IPv6 unspecified address "::";
+	// it takes precedence over EmptyHostIPv4
+	EmptyHostIPv6
+	// RequirePort rejects an address without a port (see ParseAddressRequirePort)
+	RequirePort
+	// NoIPv4Brackets rejects an IPv4 address enclosed by square brackets, e.g. "[127.0.0.1]" or
+	// "[127.0.0.1]:80", as square brackets are only meaningful for IPv6; an IPv4-mapped IPv6 address,
+	// e.g. "[::ffff:127.0.0.1]:80", is not affected. It is not set by default (nor by Strict), to
+	// preserve the leniency of ParseAddress
+	NoIPv4Brackets
+	// Strict combines all the strict options
+	Strict = StrictPort | StrictIP
+)
+
+// AddressPort pairs the address component and the Port returned by ParseAddress
+type AddressPort struct {
+	Address string
+	Port    Port
+}
+
 // ParseAddress parses the input string to validate the following:
 //  1. It has a mandatory IP address component in IPv4 dotted decimal, IPv6 or IPv4-mapped IPv6 form
 //     (see also net.ParseIP())
@@ -14,51 +72,294 @@ import (
 //     separated from the address component by ':'
 //  3. If the port exists and the address is in IPv6 or IPv4-mapped IPv6 form, the address component MUST
 //     be enclosed by square brackets ('[' and ']'), e.g. "[2001:0db8:85a3::8a2e:0370:7334]:80";
-//     in all other cases, the address component MAY be enclosed by square brackets
+//     in all other cases, the address component MAY be enclosed by square brackets. Hence, an IPv6
+//     address which is not enclosed by square brackets, e.g. "2001:db8::1", is always the address
+//     component alone, and never has a port
+//  4. An IPv6 address MAY have a non-empty zone (scope) identifier suffix, separated by '%',
+//     e.g. "fe80::1%eth0" or "[fe80::1%eth0]:80"; the zone is validated to be non-empty, and is kept
+//     attached to the returned address component
 //
 // If all validations pass, the function returns the address component as a string and the Port; otherwise,
-// an error is returned
+// an error is returned. The returned Port is never nil: if there is no port (or on error), it is
+// an unset Port, i.e. its IsSet() method returns false.
+// The validation is lenient with the spelling of the port, e.g. "127.0.0.1:080" is valid (port 80),
+// and of IPv6 addresses, e.g. "2001:0DB8::1" is valid; an IPv4 address with leading zeros, e.g.
+// "127.000.000.001", is invalid (see net.ParseIP()). See ParseAddressStrict for a strict validation.
+// A port-only input, e.g. ":8080", is invalid, as the address component is mandatory; see EmptyHost
 func ParseAddress(s string) (string, Port, error) {
 	return ParseAddressForPortTypeRange(s, All)
 }
 
 // ParseAddressForPortType behaves like ParseAddress, only that the port validation (#2) is limited
 // to the specified port type
-func ParseAddressForPortType(s string, pt portType) (string, Port, error) {
+func ParseAddressForPortType(s string, pt PortType) (string, Port, error) {
 	return ParseAddressForPortTypeRange(s, rangeOfSame(pt))
 }
 
 // ParseAddressForPortTypeRange behaves like ParseAddress, only that the port validation (#2) is limited
 // to the specified port type range
-func ParseAddressForPortTypeRange(s string, ptr *portTypeRange) (address string, p Port, err error) {
-	addr, po, hasPort := parseAddressPort(s)
-	if ip := net.ParseIP(addr); ip == nil {
+func ParseAddressForPortTypeRange(s string, ptr *portTypeRange) (string, Port, error) {
+	return ParseAddressWithOptions(s, ptr, 0)
+}
+
+// ParseAddressStrict behaves like ParseAddress, only that both the port and the address component
+// must be in canonical form (see StrictPort and StrictIP), e.g. "127.0.0.1:080" and "2001:0DB8::1"
+// are invalid
+func ParseAddressStrict(s string) (string, Port, error) {
+	return ParseAddressWithOptions(s, All, Strict)
+}
+
+// ParseAddressRequirePort behaves like ParseAddressForPortTypeRange, only that the port is mandatory,
+// e.g. "127.0.0.1" is invalid, hence the returned Port is set unless there is an error
+func ParseAddressRequirePort(s string, ptr *portTypeRange) (string, Port, error) {
+	return ParseAddressWithOptions(s, ptr, RequirePort)
+}
+
+// ParseAddressWithOptions behaves like ParseAddressForPortTypeRange, with the additional validations of opts
+func ParseAddressWithOptions(s string, ptr *portTypeRange, opts ParseOption) (address string, p Port, err error) {
+	address, _, p, err = parseHostPort(s, ptr, parseIP, opts)
+	return
+}
+
+// ParseIPAddress behaves like ParseAddress, only that it returns the parsed net.IP
+// instead of the address component string; as net.IP cannot hold a zone, the zone (if any) is dropped
+func ParseIPAddress(s string) (ip net.IP, p Port, err error) {
+	_, ip, p, err = parseHostPort(s, All, parseIP, 0)
+	return
+}
+
+// ParseHostPort behaves like ParseAddress, only that the address component may also be
+// a DNS hostname, validated per the RFC 1123 label rules (see also IsValidHostname)
+func ParseHostPort(s string) (host string, p Port, err error) {
+	host, _, p, err = parseHostPort(s, All, parseHost, 0)
+	return
+}
+
+// ParseCIDRAddress parses the input string as a CIDR notation IP address and prefix length
+// (see also net.ParseCIDR()), with an optional port, per the following rules:
+//  1. A bare CIDR, e.g. "10.0.0.0/24" or "2001:db8::/32", never has a port
+//  2. A CIDR enclosed by square brackets MAY be followed by a port, separated by ':',
+//     e.g. "[10.0.0.0/24]:80" or "[2001:db8::/32]:443"
+//  3. The IP address alone MAY be enclosed by square brackets, e.g. "[2001:db8::]/32", in which case
+//     it is equivalent to the bare CIDR, and never has a port
+//
+// If all validations pass, the function returns the network and the Port (unset if there is no port);
+// otherwise, an error is returned
+func ParseCIDRAddress(s string) (ipNet *net.IPNet, p Port, err error) {
+	p = Invalid
+	cidr := s
+	bracketed := strings.HasPrefix(cidr, leftBracket)
+	if i := strings.Index(cidr, rightBracket+slash); bracketed && i > 0 {
+		cidr, bracketed = cidr[1:i]+cidr[i+1:], false
+	}
+	addr, po, hasPort := SplitHostPort(cidr)
+	if hasPort && !bracketed {
+		err = fmt.Errorf("invalid CIDR address '%s': a port requires the CIDR to be enclosed by square brackets", s)
+		return
+	}
+	var parsed *net.IPNet
+	if _, parsed, err = net.ParseCIDR(addr); err != nil {
+		return
+	}
+	if hasPort {
+		var pp Port
+		if pp, err = newPortForTypeRange(po, All, false); err != nil {
+			return
+		}
+		p = pp
+	}
+	ipNet = parsed
+	return
+}
+
+// ParseAddresses splits s by sep (newline if sep is empty) and parses each entry using ParseAddress;
+// whitespace around each entry is trimmed, and empty entries are skipped. An error identifies
+// the first invalid entry and its (zero-based) index
+func ParseAddresses(s string, sep string) ([]AddressPort, error) {
+	if sep == empty {
+		sep = newline
+	}
+	entries := strings.Split(s, sep)
+	aps := make([]AddressPort, 0, len(entries))
+	for i, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry == empty {
+			continue
+		}
+		addr, p, err := ParseAddress(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address entry #%d '%s': %w", i, entry, err)
+		}
+		aps = append(aps, AddressPort{Address: addr, Port: p})
+	}
+	return aps, nil
+}
+
+// ValidateEndpoint validates s using ParseAddressForPortTypeRange, discarding the parsed outputs
+func ValidateEndpoint(s string, ptr *portTypeRange) (err error) {
+	_, _, err = ParseAddressForPortTypeRange(s, ptr)
+	return
+}
+
+// ValidateEndpoints validates each of ss using ValidateEndpoint, and reports all the invalid ones,
+// identified by their (zero-based) index, joined (see errors.Join)
+func ValidateEndpoints(ss []string, ptr *portTypeRange) error {
+	var errs []error
+	for i, s := range ss {
+		if err := ValidateEndpoint(s, ptr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid endpoint #%d '%s': %w", i, s, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FormatAddress is the inverse of ParseIPAddress, returning the canonical string form of the address:
+//  1. An IPv4 or IPv4-mapped IPv6 address is formatted in dotted decimal form, never enclosed
+//     by square brackets
+//  2. Any other IPv6 address is formatted per net.IP.String(), and enclosed by square brackets
+//     only if the port is set
+//  3. If the port is set, it is appended to the address, separated by ':'; if it is nil or unset,
+//     it is omitted
+//
+// If ip is not a valid IP address, an empty string is returned
+func FormatAddress(ip net.IP, p Port) string {
+	if ip.To16() == nil {
+		return empty
+	}
+	addr := ip.String()
+	if p == nil || !p.IsSet() {
+		return addr
+	}
+	if ip.To4() == nil {
+		addr = leftBracket + addr + rightBracket
+	}
+	return p.Addr(addr)
+}
+
+// JoinHostPort is the inverse of ParseHostPort: unlike net.JoinHostPort, it takes a Port, which is
+// omitted if it is nil or unset, and validates host as an IP address (with an optional zone, if IPv6)
+// or a hostname, returning an empty string if it is neither. An IPv6 host is enclosed by square brackets
+// only if the port is set; host must not be enclosed by square brackets already
+func JoinHostPort(host string, p Port) string {
+	if _, err := parseHost(host); err != nil {
+		return empty
+	}
+	if p == nil || !p.IsSet() {
+		return host
+	}
+	if strings.Contains(host, colon) {
+		host = leftBracket + host + rightBracket
+	}
+	return p.Addr(host)
+}
+
+// parseIP parses addr, which may have a zone suffix if it is an IPv6 address
+func parseIP(addr string) (ip net.IP, err error) {
+	host, zone, hasZone := strings.Cut(addr, percent)
+	if ip = net.ParseIP(host); ip == nil || (hasZone && (zone == empty || ip.To4() != nil)) {
+		ip = nil
 		err = fmt.Errorf("invalid IP address '%s'", addr)
+	}
+	return
+}
+
+// parseHost returns a nil IP (and no error) if host is a valid hostname
+func parseHost(host string) (ip net.IP, err error) {
+	if ip, err = parseIP(host); err != nil {
+		if IsValidHostname(host) {
+			err = nil
+		} else {
+			err = fmt.Errorf("invalid IP address or hostname '%s'", host)
+		}
+	}
+	return
+}
+
+func parseHostPort(s string, ptr *portTypeRange, parse func(string) (net.IP, error), opts ParseOption) (address string, ip net.IP, p Port, err error) {
+	p = Invalid
+	addr, po, hasPort := SplitHostPort(s)
+	var parsed net.IP
+	if addr == empty && hasPort && opts&(EmptyHost|EmptyHostIPv4|EmptyHostIPv6) != 0 && !strings.HasPrefix(s, leftBracket) {
+		addr, parsed = emptyHost(opts)
+	} else if parsed, err = parse(addr); err != nil {
+		return
+	}
+	if opts&NoIPv4Brackets != 0 && parsed.To4() != nil && !strings.Contains(addr, colon) &&
+		strings.HasPrefix(s, leftBracket) {
+		err = fmt.Errorf("invalid IP address '%s': an IPv4 address must not be enclosed by square brackets", addr)
+		return
+	}
+	if opts&StrictIP != 0 && parsed != nil && !isCanonicalIP(addr, parsed) {
+		err = fmt.Errorf("invalid IP address '%s': not in canonical form", addr)
 		return
 	}
+	if ip4 := parsed.To4(); opts&NormalizeIPv4 != 0 && ip4 != nil {
+		addr, parsed = ip4.String(), ip4
+	}
 	if hasPort {
-		p, err = NewPortForTypeRange(po, ptr)
+		var pp Port
+		if pp, err = newPortForTypeRange(po, ptr, false); err != nil && opts&WildcardPort != 0 {
+			if wp, wErr := newPortForTypeRange(po, All, false); wErr == nil && wp.IsWildcard() {
+				pp, err = wp, nil
+			}
+		}
+		if err == nil {
+			if opts&StrictPort != 0 && po != pp.String() {
+				err = fmt.Errorf("invalid port '%s': not in canonical form", po)
+			} else {
+				p = pp
+			}
+		}
+	} else if opts&RequirePort != 0 {
+		err = fmt.Errorf("invalid address '%s': missing port", s)
 	}
 	if err == nil {
-		address = addr
+		address, ip = addr, parsed
 	}
 	return
 }
 
-func parseAddressPort(s string) (addr, p string, hasPort bool) {
-	elems := strings.Split(s, common.Colon)
+// emptyHost returns the address component (and IP) which stands for an empty one per opts
+func emptyHost(opts ParseOption) (string, net.IP) {
+	switch {
+	case opts&EmptyHostIPv6 != 0:
+		return net.IPv6unspecified.String(), net.IPv6unspecified
+	case opts&EmptyHostIPv4 != 0:
+		return net.IPv4zero.String(), net.IPv4zero
+	}
+	return empty, nil
+}
+
+// isCanonicalIP returns true if addr (which may have a zone suffix) is the canonical form of ip
+func isCanonicalIP(addr string, ip net.IP) bool {
+	host, _, _ := strings.Cut(addr, percent)
+	if ip4 := ip.To4(); ip4 != nil && strings.Contains(host, colon) {
+		return host == mappedIPv4+ip4.String()
+	}
+	return host == ip.String()
+}
+
+// SplitHostPort splits s into the host (address component) and the port, as done by ParseAddress,
+// without validating either of them: the port is the part after the last colon, provided that it is
+// the only colon (IPv4 address or hostname) or that the part before it is enclosed by square brackets
+// (IPv6 address); otherwise, e.g. "::1" or "2001:db8::1", s is the host alone, as an IPv6 address
+// has at least two colons. Square brackets enclosing the host are removed, provided that they are balanced;
+// a stray bracket, e.g. "[127.0.0.1:80" or "::1]", is kept as part of the host, which is thus invalid
+func SplitHostPort(s string) (host, port string, hasPort bool) {
+	elems := strings.Split(s, colon)
 	if l := len(elems); l < 2 {
-		addr = s
+		host = s
 	} else {
 		n := l - 2
-		if n == 0 || (strings.HasPrefix(elems[0], common.LeftSquareBracket) && strings.HasSuffix(elems[n], common.RightSquareBracket)) {
-			p = elems[n+1]
+		if n == 0 || (strings.HasPrefix(elems[0], leftBracket) && strings.HasSuffix(elems[n], rightBracket)) {
+			port = elems[n+1]
 			hasPort = true
 		} else {
 			n++
 		}
-		addr = strings.Join(elems[:n+1], common.Colon)
+		host = strings.Join(elems[:n+1], colon)
+	}
+	if len(host) >= 2 && strings.HasPrefix(host, leftBracket) && strings.HasSuffix(host, rightBracket) {
+		host = host[1 : len(host)-1]
 	}
-	addr = strings.TrimSuffix(strings.TrimPrefix(addr, common.LeftSquareBracket), common.RightSquareBracket)
 	return
 }
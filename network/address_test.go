@@ -0,0 +1,362 @@
+package network
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseAddressIPv6(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantAddr string
+		wantPort Port
+	}{
+		// an unbracketed IPv6 address never has a port, even if its last group looks like one
+		{"::1", "::1", Invalid},
+		{"2001:db8::1", "2001:db8::1", Invalid},
+		{"fe80::1", "fe80::1", Invalid},
+		{"2001:db8::80", "2001:db8::80", Invalid},
+		{"[::1]", "::1", Invalid},
+		{"[::1]:80", "::1", port(80)},
+		{"[2001:db8::1]:443", "2001:db8::1", port(443)},
+		{"[fe80::1]:8080", "fe80::1", port(8080)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			addr, p, err := ParseAddress(tt.in)
+			if err != nil {
+				t.Fatalf("ParseAddress(%q) = %v", tt.in, err)
+			}
+			if addr != tt.wantAddr || !p.Equal(tt.wantPort) {
+				t.Errorf("ParseAddress(%q) = %q, %v, want %q, %v", tt.in, addr, p, tt.wantAddr, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestParseAddressNoIPv4Brackets(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantAddr string
+		wantPort Port
+		valid    bool
+	}{
+		{"[127.0.0.1]", "", Invalid, false},
+		{"[127.0.0.1]:80", "", Invalid, false},
+		{"127.0.0.1", "127.0.0.1", Invalid, true},
+		{"127.0.0.1:80", "127.0.0.1", port(80), true},
+		{"[::ffff:127.0.0.1]", "::ffff:127.0.0.1", Invalid, true},
+		{"[::ffff:127.0.0.1]:80", "::ffff:127.0.0.1", port(80), true},
+		{"[::1]:80", "::1", port(80), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			addr, p, err := ParseAddressWithOptions(tt.in, All, NoIPv4Brackets)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ParseAddressWithOptions(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+			if tt.valid && (addr != tt.wantAddr || !p.Equal(tt.wantPort)) {
+				t.Errorf("ParseAddressWithOptions(%q) = %q, %v, want %q, %v", tt.in, addr, p, tt.wantAddr, tt.wantPort)
+			}
+		})
+	}
+	// without the option, an IPv4 address may be enclosed by square brackets
+	for _, s := range []string{"[127.0.0.1]", "[127.0.0.1]:80"} {
+		if addr, _, err := ParseAddress(s); err != nil || addr != "127.0.0.1" {
+			t.Errorf("ParseAddress(%q) = %q, %v", s, addr, err)
+		}
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantHost string
+		wantPort string
+		hasPort  bool
+	}{
+		{"127.0.0.1", "127.0.0.1", "", false},
+		{"127.0.0.1:80", "127.0.0.1", "80", true},
+		{"[127.0.0.1]:80", "127.0.0.1", "80", true},
+		{"example.com:443", "example.com", "443", true},
+		{"::1", "::1", "", false},
+		{"[::1]", "::1", "", false},
+		{"[::1]:80", "::1", "80", true},
+		{":8080", "", "8080", true},
+		{"[127.0.0.1:80", "[127.0.0.1", "80", true},
+		{"::1]", "::1]", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			host, p, hasPort := SplitHostPort(tt.in)
+			if host != tt.wantHost || p != tt.wantPort || hasPort != tt.hasPort {
+				t.Errorf("SplitHostPort(%q) = %q, %q, %v, want %q, %q, %v", tt.in, host, p, hasPort, tt.wantHost, tt.wantPort, tt.hasPort)
+			}
+		})
+	}
+}
+
+func TestJoinHostPort(t *testing.T) {
+	tests := []struct {
+		host string
+		p    Port
+		want string
+	}{
+		{"127.0.0.1", port(80), "127.0.0.1:80"},
+		{"127.0.0.1", Invalid, "127.0.0.1"},
+		{"::1", port(80), "[::1]:80"},
+		{"::1", nil, "::1"},
+		{"example.com", port(443), "example.com:443"},
+		{"not a host", port(80), ""},
+	}
+	for _, tt := range tests {
+		if got := JoinHostPort(tt.host, tt.p); got != tt.want {
+			t.Errorf("JoinHostPort(%q, %v) = %q, want %q", tt.host, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestParseAddressesSeparators(t *testing.T) {
+	for _, sep := range []string{"", ","} {
+		s := "127.0.0.1:80\n\n [::1]:443 "
+		if sep != "" {
+			s = strings.ReplaceAll(s, "\n", sep)
+		}
+		aps, err := ParseAddresses(s, sep)
+		if err != nil {
+			t.Fatalf("ParseAddresses(%q, %q) = %v", s, sep, err)
+		}
+		if len(aps) != 2 || aps[0].Address != "127.0.0.1" || !aps[0].Port.Equal(port(80)) ||
+			aps[1].Address != "::1" || !aps[1].Port.Equal(port(443)) {
+			t.Errorf("ParseAddresses(%q, %q) = %v", s, sep, aps)
+		}
+	}
+}
+
+func TestParseAddressRejectsStrayBrackets(t *testing.T) {
+	for _, s := range []string{"[", "]", "[]", ":::", "[::]:", "a:b:c", "[::1]extra:80", "[127.0.0.1:80", "127.0.0.1]:80", "::1]", "[::1"} {
+		addr, p, err := ParseAddress(s)
+		if err == nil {
+			t.Errorf("ParseAddress(%q) = %q, %v, want an error", s, addr, p)
+		}
+	}
+}
+
+func FuzzParseAddress(f *testing.F) {
+	for _, s := range []string{"[", "]", ":::", "[::]:", "a:b:c", "[::1]extra:80", "127.0.0.1:80", "[::1]:443", "fe80::1%eth0"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		addr, p, err := ParseAddress(s)
+		if p == nil {
+			t.Fatalf("ParseAddress(%q) = nil Port", s)
+		}
+		if err != nil {
+			if addr != "" || p.IsSet() {
+				t.Fatalf("ParseAddress(%q) = %q, %v, %v, want no result on error", s, addr, p, err)
+			}
+			return
+		}
+		if p.IsSet() && !p.IsValid() {
+			t.Fatalf("ParseAddress(%q) = invalid port %v", s, p)
+		}
+		// the result must be valid, i.e. it parses back to itself
+		joined := JoinHostPort(addr, p)
+		addr2, p2, err := ParseAddress(joined)
+		if err != nil || addr2 != addr || !p2.Equal(p) {
+			t.Fatalf("ParseAddress(%q) = %q, %v, which joined as %q parses to %q, %v, %v", s, addr, p, joined, addr2, p2, err)
+		}
+	})
+}
+
+func TestParseAddressZone(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantAddr string
+		wantPort Port
+		valid    bool
+	}{
+		{"fe80::1%eth0", "fe80::1%eth0", Invalid, true},
+		{"[fe80::1%eth0]:80", "fe80::1%eth0", port(80), true},
+		{"[fe80::1%25]", "fe80::1%25", Invalid, true},
+		{"fe80::1%", "", Invalid, false},
+		{"[fe80::1%]:80", "", Invalid, false},
+		{"127.0.0.1%eth0", "", Invalid, false},
+		{"[::ffff:127.0.0.1%eth0]:80", "", Invalid, false},
+		{"%eth0", "", Invalid, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			addr, p, err := ParseAddress(tt.in)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ParseAddress(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+			if tt.valid && (addr != tt.wantAddr || !p.Equal(tt.wantPort)) {
+				t.Errorf("ParseAddress(%q) = %q, %v, want %q, %v", tt.in, addr, p, tt.wantAddr, tt.wantPort)
+			}
+		})
+	}
+	// net.IP cannot hold the zone, which is dropped
+	if ip, _, err := ParseIPAddress("[fe80::1%eth0]:80"); err != nil || ip.String() != "fe80::1" {
+		t.Errorf("ParseIPAddress() = %v, %v, want fe80::1", ip, err)
+	}
+}
+
+func TestParseAddressStrict(t *testing.T) {
+	tests := []struct {
+		in    string
+		valid bool
+	}{
+		{"127.0.0.1:80", true},
+		{"[2001:db8::1]:443", true},
+		{"[::ffff:192.0.2.1]:80", true},
+		{"[fe80::1%eth0]:80", true},
+		{"::", true},
+		{"127.0.0.1:080", false},
+		{"127.0.0.1: 80", false},
+		{"127.0.0.1:+80", false},
+		{"2001:0DB8::1", false},
+		{"2001:db8::01", false},
+		{"2001:db8:0:0:0:0:0:1", false},
+		{"2001:db8:0::1", false},
+		{"[::ffff:c000:201]:80", false},
+		{"[::FFFF:192.0.2.1]:80", false},
+		{"[FE80::1%eth0]:80", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if _, _, err := ParseAddressStrict(tt.in); (err == nil) != tt.valid {
+				t.Errorf("ParseAddressStrict(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestIsCanonicalIP(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"192.0.2.1", true},
+		{"2001:db8::1", true},
+		{"2001:db8::1%eth0", true},
+		{"::ffff:192.0.2.1", true},
+		{"::1", true},
+		{"2001:DB8::1", false},
+		{"2001:db8:0:0:1:0:0:1", false},
+		{"2001:db8::1:0:0:1", true},
+		{"::ffff:c000:201", false},
+		{"0:0:0:0:0:0:0:1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			ip, err := parseIP(tt.addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := isCanonicalIP(tt.addr, ip); got != tt.want {
+				t.Errorf("isCanonicalIP(%q) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAddressNormalizeIPv4(t *testing.T) {
+	tests := []struct {
+		in       string
+		opts     ParseOption
+		wantAddr string
+		valid    bool
+	}{
+		{"[::ffff:192.0.2.1]:80", NormalizeIPv4, "192.0.2.1", true},
+		{"::ffff:192.0.2.1", NormalizeIPv4, "192.0.2.1", true},
+		{"[::ffff:c000:201]:80", NormalizeIPv4, "192.0.2.1", true},
+		{"192.0.2.1:80", NormalizeIPv4, "192.0.2.1", true},
+		{"[2001:db8::1]:80", NormalizeIPv4, "2001:db8::1", true},
+		{"[::ffff:192.0.2.1]:80", 0, "::ffff:192.0.2.1", true},
+		// StrictIP applies to the input, before it is normalized
+		{"[::ffff:192.0.2.1]:80", NormalizeIPv4 | StrictIP, "192.0.2.1", true},
+		{"[::ffff:c000:201]:80", NormalizeIPv4 | StrictIP, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			addr, _, err := ParseAddressWithOptions(tt.in, All, tt.opts)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ParseAddressWithOptions(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+			if tt.valid && addr != tt.wantAddr {
+				t.Errorf("ParseAddressWithOptions(%q) = %q, want %q", tt.in, addr, tt.wantAddr)
+			}
+		})
+	}
+	// the returned IP is normalized too
+	if _, ip, _, err := parseHostPort("[::ffff:192.0.2.1]:80", All, parseIP, NormalizeIPv4); err != nil || len(ip) != net.IPv4len {
+		t.Errorf("parseHostPort() = %v (%d bytes), %v, want %d bytes", ip, len(ip), err, net.IPv4len)
+	}
+}
+
+func TestParseAddressEmptyHost(t *testing.T) {
+	tests := []struct {
+		in       string
+		opts     ParseOption
+		wantAddr string
+		wantIP   net.IP
+		valid    bool
+	}{
+		{":8080", EmptyHost, "", nil, true},
+		{":8080", EmptyHostIPv4, "0.0.0.0", net.IPv4zero, true},
+		{":8080", EmptyHostIPv6, "::", net.IPv6unspecified, true},
+		{":8080", EmptyHostIPv4 | EmptyHostIPv6, "::", net.IPv6unspecified, true},
+		{"127.0.0.1:8080", EmptyHost, "127.0.0.1", net.ParseIP("127.0.0.1"), true},
+		{":8080", 0, "", nil, false},
+		// the port is required
+		{"", EmptyHost, "", nil, false},
+		{":", EmptyHost, "", nil, false},
+		// empty square brackets are not an empty address component
+		{"[]:8080", EmptyHost, "", nil, false},
+		{"[]:8080", EmptyHostIPv6, "", nil, false},
+		{"[]", EmptyHost, "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			addr, ip, p, err := parseHostPort(tt.in, All, parseIP, tt.opts)
+			if (err == nil) != tt.valid {
+				t.Fatalf("parseHostPort(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+			if tt.valid && (addr != tt.wantAddr || !ip.Equal(tt.wantIP) || !p.Equal(port(8080))) {
+				t.Errorf("parseHostPort(%q) = %q, %v, %v, want %q, %v, 8080", tt.in, addr, ip, p, tt.wantAddr, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestParseHostPort(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantHost string
+		wantPort Port
+		valid    bool
+	}{
+		{"example.com", "example.com", Invalid, true},
+		{"example.com:443", "example.com", port(443), true},
+		{"localhost:8080", "localhost", port(8080), true},
+		{"127.0.0.1:80", "127.0.0.1", port(80), true},
+		{"[::1]:80", "::1", port(80), true},
+		{"[example.com]:80", "example.com", port(80), true},
+		{"example.com:70000", "", Invalid, false},
+		{"bad_host:80", "", Invalid, false},
+		{"256.1.1.1:80", "", Invalid, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			host, p, err := ParseHostPort(tt.in)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ParseHostPort(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+			if tt.valid && (host != tt.wantHost || !p.Equal(tt.wantPort)) {
+				t.Errorf("ParseHostPort(%q) = %q, %v, want %q, %v", tt.in, host, p, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// happyEyeballsDelay is the head-start of the preferred address family, per the "Connection Attempt Delay"
+// recommended by RFC 8305
+const happyEyeballsDelay = 250 * time.Millisecond
+
+// Dialer holds the options of the dial helpers (DialContext, ConnectHappyEyeballs, ResolveEndpoint),
+// which are available as its methods; the package functions use the zero Dialer
+type Dialer struct {
+	// Resolver, if set, resolves hostnames, e.g. using a custom DNS server; if nil, net.DefaultResolver is used
+	Resolver *net.Resolver
+}
+
+// resolver returns the resolver of d, net.DefaultResolver if there is none
+func (d *Dialer) resolver() *net.Resolver {
+	if d.Resolver != nil {
+		return d.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// netDialer returns a net.Dialer using the resolver of d
+func (d *Dialer) netDialer() *net.Dialer {
+	return &net.Dialer{Resolver: d.resolver()}
+}
+
+// DialContext behaves like the DialContext method of the zero Dialer
+func DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+// ConnectHappyEyeballs behaves like the ConnectHappyEyeballs method of the zero Dialer
+func ConnectHappyEyeballs(ctx context.Context, host string, p Port) (net.Conn, error) {
+	var d Dialer
+	return d.ConnectHappyEyeballs(ctx, host, p)
+}
+
+// DialContext validates addr like ParseHostPort (i.e. the address component may be an IP address
+// or a hostname), and then connects to it using a net.Dialer, with the resolver of d, on the named network
+// (see net.Dial). The port is required, as there is no default port to connect to, and must not be
+// the wildcard port 0 (see Port.IsWildcard). If addr is invalid, an error is returned before any network call is made
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, p, err := ParseHostPort(addr)
+	if err == nil {
+		err = validDialPort(p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid address '%s': %w", addr, err)
+	}
+	return d.netDialer().DialContext(ctx, network, net.JoinHostPort(host, p.String()))
+}
+
+// ConnectHappyEyeballs connects to host (an IP address or a hostname) on TCP port p, relying on the
+// Happy Eyeballs (RFC 6555) support of net.Dialer: the host is resolved, and the addresses of the first
+// address family (per the resolver order, typically IPv6) are dialed one after the other, with a head-start
+// of 250 ms (the "Connection Attempt Delay" of RFC 8305, rather than the 300 ms default of net.Dialer),
+// after which the addresses of the other address family are dialed in parallel; the first successful
+// connection is returned, and the other dial is cancelled. The address families are not interleaved
+// beyond that. host and p are validated like DialContext, before any network call is made
+func (d *Dialer) ConnectHappyEyeballs(ctx context.Context, host string, p Port) (net.Conn, error) {
+	if _, err := parseHost(host); err != nil {
+		return nil, err
+	}
+	if err := validDialPort(p); err != nil {
+		return nil, fmt.Errorf("invalid address '%s': %w", JoinHostPort(host, p), err)
+	}
+	nd := d.netDialer()
+	nd.FallbackDelay = happyEyeballsDelay
+	return nd.DialContext(ctx, tcp, net.JoinHostPort(host, p.String()))
+}
+
+// validDialPort checks that p can be connected to
+func validDialPort(p Port) (err error) {
+	if p == nil || !p.IsSet() {
+		err = fmt.Errorf("missing port")
+	} else if p.IsWildcard() {
+		err = fmt.Errorf("cannot connect to the wildcard port %v", p)
+	}
+	return
+}
@@ -0,0 +1,120 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// listenLoopback listens on a free port of 127.0.0.1 until the end of the test, and returns that port
+func listenLoopback(t *testing.T) Port {
+	ln, err := net.Listen(tcp, loopbackAnyPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	return port(ln.Addr().(*net.TCPAddr).Port)
+}
+
+// failingDialer returns a Dialer whose resolver fails to reach its DNS server, and records that it has tried
+func failingDialer() (*Dialer, *atomic.Bool) {
+	var called atomic.Bool
+	return &Dialer{Resolver: &net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			called.Store(true)
+			return nil, errors.New("no DNS server")
+		},
+	}}, &called
+}
+
+func TestDialContext(t *testing.T) {
+	p := listenLoopback(t)
+	conn, err := DialContext(context.Background(), tcp, p.Addr("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("DialContext() = %v", err)
+	}
+	_ = conn.Close()
+	tests := []string{"127.0.0.1", "127.0.0.1:0", "bad host!:80", "[::1]", "127.0.0.1:65536"}
+	for _, addr := range tests {
+		t.Run(addr, func(t *testing.T) {
+			d, called := failingDialer()
+			if conn, err := d.DialContext(context.Background(), tcp, addr); err == nil {
+				_ = conn.Close()
+				t.Fatalf("DialContext(%q) succeeded", addr)
+			}
+			if called.Load() {
+				t.Errorf("DialContext(%q) made a network call", addr)
+			}
+		})
+	}
+}
+
+func TestDialerResolver(t *testing.T) {
+	d, called := failingDialer()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if conn, err := d.DialContext(ctx, tcp, "unresolvable.test:80"); err == nil {
+		_ = conn.Close()
+		t.Fatal("DialContext() succeeded")
+	}
+	if !called.Load() {
+		t.Error("DialContext() did not use the resolver of the Dialer")
+	}
+	called.Store(false)
+	if _, err := d.ResolveEndpoint(ctx, "unresolvable.test:80"); err == nil {
+		t.Fatal("ResolveEndpoint() succeeded")
+	}
+	if !called.Load() {
+		t.Error("ResolveEndpoint() did not use the resolver of the Dialer")
+	}
+}
+
+func TestConnectHappyEyeballs(t *testing.T) {
+	p := listenLoopback(t)
+	// localhost typically resolves to ::1 too, on which nothing listens: the dial falls back to 127.0.0.1
+	for _, host := range []string{"127.0.0.1", "localhost"} {
+		t.Run(host, func(t *testing.T) {
+			conn, err := ConnectHappyEyeballs(context.Background(), host, p)
+			if err != nil {
+				t.Fatalf("ConnectHappyEyeballs(%q) = %v", host, err)
+			}
+			_ = conn.Close()
+		})
+	}
+	tests := []struct {
+		name string
+		host string
+		p    Port
+	}{
+		{"invalid host", "bad host!", port(80)},
+		{"bracketed host", "[::1]", port(80)},
+		{"nil port", "127.0.0.1", nil},
+		{"unset port", "127.0.0.1", Invalid},
+		{"wildcard port", "127.0.0.1", port(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, called := failingDialer()
+			if conn, err := d.ConnectHappyEyeballs(context.Background(), tt.host, tt.p); err == nil {
+				_ = conn.Close()
+				t.Fatalf("ConnectHappyEyeballs(%q, %v) succeeded", tt.host, tt.p)
+			}
+			if called.Load() {
+				t.Errorf("ConnectHappyEyeballs(%q, %v) made a network call", tt.host, tt.p)
+			}
+		})
+	}
+}
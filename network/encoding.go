@@ -0,0 +1,161 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	jsonNull = "null"
+)
+
+// PortValue wraps a Port so that it can be used as a field of structs which are
+// marshalled / unmarshalled (the concrete port type is unexported, therefore
+// a Port-typed field cannot be unmarshalled into directly); the zero PortValue
+// holds no Port, and is marshalled as null. The Port is a named field rather than
+// embedded, so that a PortValue, which may hold no Port, is not used as a Port
+type PortValue struct {
+	Port Port
+}
+
+// String implements fmt.Stringer, returning the String of the Port, or "<unset>" if there is none
+func (pv PortValue) String() string {
+	if pv.Port == nil {
+		return unset
+	}
+	return pv.Port.String()
+}
+
+// MarshalJSON encodes the port as a JSON number; an unset port is not marshalled
+func (p port) MarshalJSON() ([]byte, error) {
+	if err := p.checkMarshal(); err != nil {
+		return nil, err
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number, validating it using NewPort; null is a no-op
+func (p *port) UnmarshalJSON(data []byte) (err error) {
+	if string(data) == jsonNull {
+		return
+	}
+	var pp Port
+	if pp, err = unmarshalJSON(data); err == nil {
+		*p = pp.(port)
+	}
+	return
+}
+
+func (pv PortValue) MarshalJSON() ([]byte, error) {
+	if pv.Port == nil {
+		return []byte(jsonNull), nil
+	}
+	return json.Marshal(pv.Port)
+}
+
+func (pv *PortValue) UnmarshalJSON(data []byte) (err error) {
+	if string(data) == jsonNull {
+		pv.Port = nil
+		return
+	}
+	var p Port
+	if p, err = unmarshalJSON(data); err == nil {
+		pv.Port = p
+	}
+	return
+}
+
+// MarshalYAML encodes the port as a decimal integer; an unset port is not marshalled
+func (p port) MarshalYAML() (interface{}, error) {
+	if err := p.checkMarshal(); err != nil {
+		return nil, err
+	}
+	return uint64(p), nil
+}
+
+// UnmarshalYAML decodes an integer node, validating it using NewPort
+func (p *port) UnmarshalYAML(node *yaml.Node) (err error) {
+	var pp Port
+	if pp, err = unmarshalYAML(node); err == nil {
+		*p = pp.(port)
+	}
+	return
+}
+
+func (pv PortValue) MarshalYAML() (interface{}, error) {
+	if pv.Port == nil {
+		return nil, nil
+	}
+	return pv.Port, nil
+}
+
+func (pv *PortValue) UnmarshalYAML(node *yaml.Node) (err error) {
+	var p Port
+	if p, err = unmarshalYAML(node); err == nil {
+		pv.Port = p
+	}
+	return
+}
+
+// MarshalText encodes the port as decimal text; an unset port is not marshalled
+func (p port) MarshalText() ([]byte, error) {
+	if err := p.checkMarshal(); err != nil {
+		return nil, err
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes decimal text, validating it using NewPort
+func (p *port) UnmarshalText(text []byte) (err error) {
+	var pp Port
+	if pp, err = NewPort(string(text)); err == nil {
+		*p = pp.(port)
+	}
+	return
+}
+
+func (pv PortValue) MarshalText() ([]byte, error) {
+	if pv.Port == nil {
+		return []byte{}, nil
+	}
+	if !pv.Port.IsSet() {
+		return nil, fmt.Errorf("cannot marshal unset port")
+	}
+	return []byte(pv.Port.String()), nil
+}
+
+func (pv *PortValue) UnmarshalText(text []byte) (err error) {
+	if len(text) == 0 {
+		pv.Port = nil
+		return
+	}
+	var p Port
+	if p, err = NewPort(string(text)); err == nil {
+		pv.Port = p
+	}
+	return
+}
+
+func (p port) checkMarshal() (err error) {
+	if !p.IsSet() {
+		err = fmt.Errorf("cannot marshal unset port")
+	}
+	return
+}
+
+func unmarshalJSON(data []byte) (p Port, err error) {
+	var n uint64
+	if err = json.Unmarshal(data, &n); err == nil {
+		p, err = NewPort(n)
+	}
+	return
+}
+
+func unmarshalYAML(node *yaml.Node) (p Port, err error) {
+	var n uint64
+	if err = node.Decode(&n); err == nil {
+		p, err = NewPort(n)
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPortValueJSON(t *testing.T) {
+	type config struct {
+		Port PortValue `json:"port"`
+	}
+	tests := []struct {
+		name string
+		in   config
+		want string
+	}{
+		{"port", config{Port: PortValue{Port: port(8080)}}, `{"port":8080}`},
+		{"zero value", config{}, `{"port":null}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", data, tt.want)
+			}
+			var out config
+			if err = json.Unmarshal(data, &out); err != nil {
+				t.Fatal(err)
+			}
+			if (out.Port.Port == nil) != (tt.in.Port.Port == nil) || (out.Port.Port != nil && out.Port.Port.Uint64() != tt.in.Port.Port.Uint64()) {
+				t.Errorf("json.Unmarshal(%s) = %v, want %v", data, out.Port, tt.in.Port)
+			}
+		})
+	}
+	var out config
+	if err := json.Unmarshal([]byte(`{"port":70000}`), &out); err == nil {
+		t.Errorf("json.Unmarshal() of an invalid port = %v, nil error", out.Port)
+	}
+}
+
+func TestPortValueString(t *testing.T) {
+	if got := (PortValue{}).String(); got != "<unset>" {
+		t.Errorf("PortValue{}.String() = %q, want %q", got, "<unset>")
+	}
+	if got := (PortValue{Port: port(443)}).String(); got != "443" {
+		t.Errorf("String() = %q, want %q", got, "443")
+	}
+}
@@ -0,0 +1,90 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Endpoint is the structured result of ParseEndpoint
+type Endpoint struct {
+	// Host is the address component, without the zone
+	Host string
+	// IP is the parsed address component
+	IP net.IP
+	// Zone is the IPv6 zone (scope) identifier, empty if there is none
+	Zone string
+	// Port is never nil; it is unset if there is no port
+	Port Port
+}
+
+// ParseEndpoint behaves like ParseAddress, only that it returns the parsed components as an Endpoint
+func ParseEndpoint(s string) (*Endpoint, error) {
+	return ParseEndpointWithOptions(s, All, 0)
+}
+
+// ParseEndpointWithOptions behaves like ParseAddressWithOptions, only that it returns the parsed
+// components as an Endpoint
+func ParseEndpointWithOptions(s string, ptr *portTypeRange, opts ParseOption) (*Endpoint, error) {
+	addr, ip, p, err := parseHostPort(s, ptr, parseIP, opts)
+	if err != nil {
+		return nil, err
+	}
+	host, zone, _ := strings.Cut(addr, percent)
+	return &Endpoint{Host: host, IP: ip, Zone: zone, Port: p}, nil
+}
+
+// HasPort returns true if the endpoint has a set port
+func (e *Endpoint) HasPort() bool {
+	return e != nil && e.Port != nil && e.Port.IsSet()
+}
+
+// Address returns the address component, with the zone (if any) attached, as returned by ParseAddress
+func (e *Endpoint) Address() (addr string) {
+	if e != nil {
+		if addr = e.Host; e.Zone != empty {
+			addr += percent + e.Zone
+		}
+	}
+	return
+}
+
+// String implements fmt.Stringer, returning the address component followed by the port (if set),
+// separated by ':'; an IPv6 address component is enclosed by square brackets if the port is set
+func (e *Endpoint) String() string {
+	addr := e.Address()
+	if !e.HasPort() {
+		return addr
+	}
+	if strings.Contains(addr, colon) {
+		addr = leftBracket + addr + rightBracket
+	}
+	return e.Port.Addr(addr)
+}
+
+// ResolveEndpoint behaves like the ResolveEndpoint method of the zero Dialer
+func ResolveEndpoint(ctx context.Context, s string) ([]Endpoint, error) {
+	var d Dialer
+	return d.ResolveEndpoint(ctx, s)
+}
+
+// ResolveEndpoint validates s like ParseHostPort (i.e. the address component may be an IP address
+// or a hostname, with an optional port), and resolves the address component using the resolver of d,
+// returning an Endpoint per resolved IP address, each with the port of s (unset if there is none).
+// An IP address resolves to itself, without a DNS lookup
+func (d *Dialer) ResolveEndpoint(ctx context.Context, s string) ([]Endpoint, error) {
+	host, p, err := ParseHostPort(s)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := d.resolver().LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve '%s': %w", host, err)
+	}
+	endpoints := make([]Endpoint, len(addrs))
+	for i, addr := range addrs {
+		endpoints[i] = Endpoint{Host: addr.IP.String(), IP: addr.IP, Zone: addr.Zone, Port: p}
+	}
+	return endpoints, nil
+}
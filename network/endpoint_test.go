@@ -0,0 +1,90 @@
+package network
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Endpoint
+		address string
+		str     string
+	}{
+		{"127.0.0.1:80", Endpoint{Host: "127.0.0.1", IP: net.ParseIP("127.0.0.1"), Port: port(80)}, "127.0.0.1", "127.0.0.1:80"},
+		{"127.0.0.1", Endpoint{Host: "127.0.0.1", IP: net.ParseIP("127.0.0.1"), Port: Invalid}, "127.0.0.1", "127.0.0.1"},
+		{"[::1]:443", Endpoint{Host: "::1", IP: net.IPv6loopback, Port: port(443)}, "::1", "[::1]:443"},
+		{"::1", Endpoint{Host: "::1", IP: net.IPv6loopback, Port: Invalid}, "::1", "::1"},
+		{"[fe80::1%eth0]:80", Endpoint{Host: "fe80::1", IP: net.ParseIP("fe80::1"), Zone: "eth0", Port: port(80)}, "fe80::1%eth0", "[fe80::1%eth0]:80"},
+		{"[127.0.0.1]:80", Endpoint{Host: "127.0.0.1", IP: net.ParseIP("127.0.0.1"), Port: port(80)}, "127.0.0.1", "127.0.0.1:80"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			e, err := ParseEndpoint(tt.in)
+			if err != nil {
+				t.Fatalf("ParseEndpoint(%q) = %v", tt.in, err)
+			}
+			if e.Host != tt.want.Host || !e.IP.Equal(tt.want.IP) || e.Zone != tt.want.Zone || !e.Port.Equal(tt.want.Port) {
+				t.Errorf("ParseEndpoint(%q) = %+v, want %+v", tt.in, *e, tt.want)
+			}
+			if e.HasPort() != tt.want.Port.IsSet() {
+				t.Errorf("HasPort() = %v, want %v", e.HasPort(), tt.want.Port.IsSet())
+			}
+			if got := e.Address(); got != tt.address {
+				t.Errorf("Address() = %q, want %q", got, tt.address)
+			}
+			if got := e.String(); got != tt.str {
+				t.Errorf("String() = %q, want %q", got, tt.str)
+			}
+		})
+	}
+	for _, s := range []string{"", "example.com:80", "127.0.0.1:65536", "[::1", "fe80::1%"} {
+		if e, err := ParseEndpoint(s); err == nil {
+			t.Errorf("ParseEndpoint(%q) = %+v, want an error", s, *e)
+		}
+	}
+	var e *Endpoint
+	if e.HasPort() || e.Address() != "" {
+		t.Errorf("a nil Endpoint has a port or an address")
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"127.0.0.1:80", []string{"127.0.0.1:80"}},
+		{"127.0.0.1", []string{"127.0.0.1"}},
+		{"[::1]:443", []string{"[::1]:443"}},
+		{"[fe80::1%eth0]:80", []string{"[fe80::1%eth0]:80"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			// IP addresses resolve to themselves, without a DNS lookup
+			d, called := failingDialer()
+			endpoints, err := d.ResolveEndpoint(context.Background(), tt.in)
+			if err != nil {
+				t.Fatalf("ResolveEndpoint(%q) = %v", tt.in, err)
+			}
+			if called.Load() {
+				t.Errorf("ResolveEndpoint(%q) made a DNS lookup", tt.in)
+			}
+			if len(endpoints) != len(tt.want) {
+				t.Fatalf("ResolveEndpoint(%q) = %v, want %v", tt.in, endpoints, tt.want)
+			}
+			for i, e := range endpoints {
+				if got := e.String(); got != tt.want[i] {
+					t.Errorf("ResolveEndpoint(%q)[%d] = %q, want %q", tt.in, i, got, tt.want[i])
+				}
+			}
+		})
+	}
+	for _, in := range []string{"bad host!:80", "127.0.0.1:65536"} {
+		if _, err := ResolveEndpoint(context.Background(), in); err == nil {
+			t.Errorf("ResolveEndpoint(%q) succeeded", in)
+		}
+	}
+}
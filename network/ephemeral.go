@@ -0,0 +1,34 @@
+package network
+
+import "fmt"
+
+// IsEphemeralOSRange returns true if p is in the range of the ephemeral ports of the OS, i.e. the
+// ports it assigns to outbound connections (e.g. 32768-60999 on many Linux systems), hence binding
+// p may collide with them. The range is read from /proc/sys/net/ipv4/ip_local_port_range on Linux,
+// and from the net.inet.ip.portrange sysctls on macOS; if it cannot be read (or on any other OS),
+// the IANA Dynamic range is used instead. An error is returned if p is unset or invalid
+func IsEphemeralOSRange(p Port) (ok bool, err error) {
+	if p == nil || !p.IsValid() {
+		err = fmt.Errorf("invalid port %v", p)
+		return
+	}
+	pr, rErr := osEphemeralRange()
+	if rErr != nil {
+		pr = ranges[Dynamic]
+	}
+	ok = p.IsValidForPortRange(pr)
+	return
+}
+
+// ephemeralRange returns the range of the ports from min to max, as read from the OS
+func ephemeralRange(min, max uint64) (*portRange, error) {
+	pMin, err := NewPort(min)
+	if err != nil {
+		return nil, err
+	}
+	pMax, err := NewPort(max)
+	if err != nil {
+		return nil, err
+	}
+	return newPortRange(pMin, pMax)
+}
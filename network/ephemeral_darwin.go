@@ -0,0 +1,16 @@
+package network
+
+import "syscall"
+
+// osEphemeralRange reads the ephemeral port range of macOS, per the net.inet.ip.portrange sysctls
+func osEphemeralRange() (*portRange, error) {
+	first, err := syscall.SysctlUint32("net.inet.ip.portrange.first")
+	if err != nil {
+		return nil, err
+	}
+	last, err := syscall.SysctlUint32("net.inet.ip.portrange.last")
+	if err != nil {
+		return nil, err
+	}
+	return ephemeralRange(uint64(first), uint64(last))
+}
@@ -0,0 +1,29 @@
+package network
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const ipLocalPortRange = "/proc/sys/net/ipv4/ip_local_port_range"
+
+// osEphemeralRange reads the ephemeral port range of Linux, e.g. "32768	60999"
+func osEphemeralRange() (*portRange, error) {
+	data, err := os.ReadFile(ipLocalPortRange)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid ephemeral port range '%s'", strings.TrimSpace(string(data)))
+	}
+	var bounds [2]uint64
+	for i, field := range fields {
+		if bounds[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid ephemeral port range '%s': %w", strings.TrimSpace(string(data)), err)
+		}
+	}
+	return ephemeralRange(bounds[0], bounds[1])
+}
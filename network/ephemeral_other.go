@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package network
+
+import "fmt"
+
+// osEphemeralRange is not supported on this OS, hence IsEphemeralOSRange uses the Dynamic range
+func osEphemeralRange() (*portRange, error) {
+	return nil, fmt.Errorf("reading the ephemeral port range is not supported")
+}
@@ -0,0 +1,63 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestIsEphemeralOSRange(t *testing.T) {
+	pr, err := osEphemeralRange()
+	if err != nil {
+		t.Logf("the ephemeral port range of the OS cannot be read (%v), the Dynamic range applies", err)
+		pr = ranges[Dynamic]
+	}
+	min, max := pr.Min().Uint64(), pr.Max().Uint64()
+	tests := []struct {
+		p    Port
+		want bool
+	}{
+		{port(min), true},
+		{port((min + max) / 2), true},
+		{port(max), true},
+		{port(min - 1), false},
+		{port(1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.p.String(), func(t *testing.T) {
+			ok, err := IsEphemeralOSRange(tt.p)
+			if err != nil {
+				t.Fatalf("IsEphemeralOSRange(%v) = %v", tt.p, err)
+			}
+			if ok != tt.want {
+				t.Errorf("IsEphemeralOSRange(%v) = %v, want %v (range %v)", tt.p, ok, tt.want, pr)
+			}
+		})
+	}
+	if max < All.Max().Uint64() {
+		if ok, err := IsEphemeralOSRange(port(max + 1)); ok || err != nil {
+			t.Errorf("IsEphemeralOSRange(%d) = %v, %v, want false", max+1, ok, err)
+		}
+	}
+	for _, p := range []Port{nil, Invalid} {
+		if _, err := IsEphemeralOSRange(p); err == nil {
+			t.Errorf("IsEphemeralOSRange(%v) = nil error", p)
+		}
+	}
+}
+
+func TestEphemeralRange(t *testing.T) {
+	tests := []struct {
+		min, max uint64
+		valid    bool
+	}{
+		{32768, 60999, true},
+		{49152, 65535, true},
+		{1024, 1024, true},
+		{60999, 32768, false},
+		{32768, 65536, false},
+	}
+	for _, tt := range tests {
+		if pr, err := ephemeralRange(tt.min, tt.max); (err == nil) != tt.valid {
+			t.Errorf("ephemeralRange(%d, %d) = %v, %v, valid %v", tt.min, tt.max, pr, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package network
+
+import (
+	"flag"
+)
+
+// PortFlag is a flag.Value holding a Port, validated using NewPort, e.g.
+// flag.Var(network.NewPortFlag(p), "port", "the port to listen on"); as it also has a Type method,
+// it implements the pflag.Value interface of github.com/spf13/pflag too, hence it can be used
+// with cobra commands, without depending on pflag
+type PortFlag struct {
+	p Port
+}
+
+const portFlagType = "port"
+
+var _ flag.Value = (*PortFlag)(nil)
+
+// NewPortFlag returns a PortFlag holding def as the default value (nil means an unset port)
+func NewPortFlag(def Port) *PortFlag {
+	if def == nil {
+		def = Invalid
+	}
+	return &PortFlag{p: def}
+}
+
+// String implements flag.Value, returning the decimal representation of the port, or "<unset>"
+func (pf *PortFlag) String() string {
+	if pf == nil || pf.p == nil {
+		return unset
+	}
+	return pf.p.String()
+}
+
+// Set implements flag.Value, validating s using NewPort
+func (pf *PortFlag) Set(s string) error {
+	p, err := NewPort(s)
+	if err != nil {
+		return err
+	}
+	pf.p = p
+	return nil
+}
+
+// Type implements pflag.Value, returning the name of the flag type, "port"
+func (pf *PortFlag) Type() string {
+	return portFlagType
+}
+
+// Port returns the port held by pf, which is never nil (unset if neither a default nor a value has been set)
+func (pf *PortFlag) Port() Port {
+	if pf == nil || pf.p == nil {
+		return Invalid
+	}
+	return pf.p
+}
@@ -0,0 +1,50 @@
+package network
+
+import (
+	"strings"
+)
+
+const (
+	dot               = "."
+	hyphen            = '-'
+	maxHostnameLength = 253
+	maxLabelLength    = 63
+)
+
+// IsValidHostname returns true if s is a legal DNS hostname per RFC 1123: at most 253 characters
+// (excluding an optional trailing dot), made of dot-separated labels of 1 to 63 letters, digits
+// and hyphens, neither starting nor ending with a hyphen; the top-level label must not be
+// all-numeric, so that malformed IPv4 addresses are not mistaken for hostnames
+func IsValidHostname(s string) bool {
+	s = strings.TrimSuffix(s, dot)
+	if l := len(s); l == 0 || l > maxHostnameLength {
+		return false
+	}
+	labels := strings.Split(s, dot)
+	for _, label := range labels {
+		if !validLabel(label) {
+			return false
+		}
+	}
+	return strings.ContainsFunc(labels[len(labels)-1], func(r rune) bool { return !isDigit(r) })
+}
+
+func validLabel(label string) bool {
+	if l := len(label); l == 0 || l > maxLabelLength || label[0] == hyphen || label[l-1] == hyphen {
+		return false
+	}
+	for _, r := range label {
+		if !isDigit(r) && !isLetter(r) && r != hyphen {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
@@ -0,0 +1,54 @@
+package network
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsValidHostname(t *testing.T) {
+	label63 := strings.Repeat("a", 63)
+	// 4 labels of 63 characters and 3 dots: 255 characters
+	long := strings.Join([]string{label63, label63, label63, label63}, ".")
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"localhost", true},
+		{"example.com", true},
+		{"example.com.", true},
+		{"EXAMPLE.com", true},
+		{"my-host.example-1.com", true},
+		{"1host.com", true},
+		{"host.c0m", true},
+		{label63 + ".com", true},
+		{long[:253], true},
+		{long[:253] + ".", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{".example.com", false},
+		{"example..com", false},
+		{"-host.com", false},
+		{"host-.com", false},
+		{"host_name.com", false},
+		{"host name", false},
+		{"hôst.com", false},
+		{"a" + label63 + ".com", false},
+		{long[:254], false},
+		{long, false},
+		// an all-numeric top-level label is a malformed IPv4 address, not a hostname
+		{"127.0.0.1", false},
+		{"256.1.1.1", false},
+		{"127.000.000.001", false},
+		{"1234", false},
+		{"::1", false},
+		{"[::1]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := IsValidHostname(tt.s); got != tt.want {
+				t.Errorf("IsValidHostname(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
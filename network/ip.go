@@ -0,0 +1,35 @@
+package network
+
+import (
+	"net"
+)
+
+// The following functions classify an address component as returned by ParseAddress
+// (i.e. possibly with an IPv6 zone); an IPv4-mapped IPv6 address is classified as its
+// IPv4 equivalent, and an invalid address is never classified
+
+// IsLoopbackAddress returns true if addr is a loopback address
+func IsLoopbackAddress(addr string) bool {
+	return classify(addr, net.IP.IsLoopback)
+}
+
+// IsLinkLocalAddress returns true if addr is a link-local unicast or multicast address
+func IsLinkLocalAddress(addr string) bool {
+	return classify(addr, net.IP.IsLinkLocalUnicast) || classify(addr, net.IP.IsLinkLocalMulticast)
+}
+
+// IsPrivateAddress returns true if addr is a private address, per RFC 1918 (IPv4)
+// or RFC 4193 (IPv6)
+func IsPrivateAddress(addr string) bool {
+	return classify(addr, net.IP.IsPrivate)
+}
+
+// IsMulticastAddress returns true if addr is a multicast address
+func IsMulticastAddress(addr string) bool {
+	return classify(addr, net.IP.IsMulticast)
+}
+
+func classify(addr string, is func(net.IP) bool) bool {
+	ip, err := parseIP(addr)
+	return err == nil && is(ip)
+}
@@ -0,0 +1,119 @@
+package network
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+const (
+	tcp                  = "tcp"
+	loopbackAnyPort      = "127.0.0.1:0"
+	loopbackPortFormat   = "127.0.0.1:%d"
+	findFreePortAttempts = 16
+	defaultInUseTimeout  = time.Second
+)
+
+// FindFreePort returns an available TCP port of the loopback interface (127.0.0.1) within the given
+// port type range. It first asks the OS for a port, which it picks from its ephemeral port range
+// (see IsEphemeralOSRange), hence this suits Dynamic, NonSystem and All; if the OS hands back
+// ports outside the range, e.g. for Registered or NonDynamic, it binds the ports of the range
+// one by one, from a random one, until one is free. Binding a System port (below 1024) usually
+// requires privileges, e.g. root or CAP_NET_BIND_SERVICE on Linux, hence FindFreePort fails for
+// System otherwise, with the error of the last bind. The returned port is never the wildcard
+// port 0 (see Port.IsWildcard). The port is released before FindFreePort returns, hence another
+// process may bind it before the caller does
+func FindFreePort(ptr *portTypeRange) (p Port, err error) {
+	var l net.Listener
+	if l, p, err = listenFreePort(ptr); err == nil {
+		if err = l.Close(); err != nil {
+			p = nil
+		}
+	}
+	return
+}
+
+// ReservePort behaves like FindFreePort, only that the port is kept bound by a listener until release
+// is called, so that the caller controls when the port is handed off, e.g. right before the server which
+// is to use it binds it. Another process may still bind the port between release and the bind of the
+// server, but this window is far smaller than that of FindFreePort. If err is not nil, release is nil
+func ReservePort(ptr *portTypeRange) (p Port, release func() error, err error) {
+	var l net.Listener
+	if l, p, err = listenFreePort(ptr); err == nil {
+		release = l.Close
+	}
+	return
+}
+
+// IsPortInUse behaves like IsPortInUseWithTimeout, with a default timeout of one second
+func IsPortInUse(host string, p Port) (bool, error) {
+	return IsPortInUseWithTimeout(host, p, defaultInUseTimeout)
+}
+
+// IsPortInUseWithTimeout attempts a TCP connection to the port on host, to determine whether
+// the port is already bound: a successful connection means the port is in use, a refused connection
+// means it is free. Any other failure (timeout, permission, name resolution etc.) is returned
+// as an error, as it is inconclusive
+func IsPortInUseWithTimeout(host string, p Port, timeout time.Duration) (inUse bool, err error) {
+	if p == nil || !p.IsValid() {
+		err = fmt.Errorf("invalid port %v", p)
+		return
+	}
+	var conn net.Conn
+	if conn, err = net.DialTimeout(tcp, net.JoinHostPort(host, p.String()), timeout); err == nil {
+		inUse = true
+		_ = conn.Close()
+	} else if isConnRefused(err) {
+		err = nil
+	}
+	return
+}
+
+// listenFreePort returns a listener bound to a free port within ptr, on the loopback interface; listeners
+// bound by the OS to ports outside ptr are kept open until the search is over, so that the OS does not
+// hand them back. If the OS hands back no port within ptr, the ports of ptr are bound one by one
+func listenFreePort(ptr *portTypeRange) (l net.Listener, p Port, err error) {
+	lo, hi, ok := ptr.bounds()
+	if !ok {
+		err = fmt.Errorf("invalid port type range")
+		return
+	}
+	var rejected []net.Listener
+	defer func() {
+		for _, r := range rejected {
+			_ = r.Close()
+		}
+	}()
+	for i := 0; i < findFreePortAttempts; i++ {
+		var candidate net.Listener
+		if candidate, err = net.Listen(tcp, loopbackAnyPort); err != nil {
+			return
+		}
+		if cp, cErr := listenerPort(candidate); cErr == nil && cp.IsValidForTypeRange(ptr) {
+			l, p = candidate, cp
+			return
+		}
+		rejected = append(rejected, candidate)
+	}
+	// the wildcard port 0 is not a candidate, as the OS binds another port instead
+	lo = max(lo, 1)
+	n := uint64(hi - lo + 1)
+	start := uint64(rand.Int63n(int64(n)))
+	for i := uint64(0); i < n; i++ {
+		cp := lo + port((start+i)%n)
+		if l, err = net.Listen(tcp, fmt.Sprintf(loopbackPortFormat, cp)); err == nil {
+			p = cp
+			return
+		}
+	}
+	err = fmt.Errorf("no free port found in range %v: %w", ptr, err)
+	return
+}
+
+func listenerPort(l net.Listener) (Port, error) {
+	if addr, ok := l.Addr().(*net.TCPAddr); ok {
+		return NewPort(addr.Port)
+	}
+	return nil, fmt.Errorf("unexpected listener address %v", l.Addr())
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package network
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isConnRefused returns true if err is a refused connection
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
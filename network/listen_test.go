@@ -0,0 +1,70 @@
+package network
+
+import (
+	"net"
+	"testing"
+)
+
+func TestFindFreePort(t *testing.T) {
+	for _, ptr := range []*portTypeRange{All, NonSystem, NonDynamic, rangeOfSame(Registered), rangeOfSame(Dynamic)} {
+		t.Run(ptr.String(), func(t *testing.T) {
+			p, err := FindFreePort(ptr)
+			if err != nil {
+				t.Fatalf("FindFreePort() = %v", err)
+			}
+			if !ptr.Contains(p) || p.IsWildcard() {
+				t.Fatalf("FindFreePort() = %v, not in %v", p, ptr)
+			}
+			// the port has been released, hence it can be bound
+			l, err := net.Listen(tcp, p.Addr("127.0.0.1"))
+			if err != nil {
+				t.Fatalf("port %v is not free: %v", p, err)
+			}
+			_ = l.Close()
+		})
+	}
+}
+
+func TestFindFreePortSystem(t *testing.T) {
+	// binding a system port requires privileges, without which the error is that of the bind
+	p, err := FindFreePort(rangeOfSame(System))
+	if err != nil {
+		t.Logf("FindFreePort(System) = %v", err)
+		return
+	}
+	if !rangeOfSame(System).Contains(p) || p.IsWildcard() {
+		t.Errorf("FindFreePort(System) = %v", p)
+	}
+}
+
+func TestFindFreePortInvalidRange(t *testing.T) {
+	if p, err := FindFreePort(nil); err == nil {
+		t.Errorf("FindFreePort(nil) = %v, nil error", p)
+	}
+}
+
+func TestReservePort(t *testing.T) {
+	p, release, err := ReservePort(NonSystem)
+	if err != nil {
+		t.Fatalf("ReservePort() = %v", err)
+	}
+	if !NonSystem.Contains(p) || p.IsWildcard() {
+		t.Fatalf("ReservePort() = %v, not in %v", p, NonSystem)
+	}
+	// the port is held until it is released
+	if l, err := net.Listen(tcp, p.Addr("127.0.0.1")); err == nil {
+		_ = l.Close()
+		t.Fatalf("port %v is not held", p)
+	}
+	if err = release(); err != nil {
+		t.Fatalf("release() = %v", err)
+	}
+	l, err := net.Listen(tcp, p.Addr("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("port %v is not free after release: %v", p, err)
+	}
+	_ = l.Close()
+	if p, release, err = ReservePort(nil); err == nil || release != nil {
+		t.Errorf("ReservePort(nil) = %v, %v", p, err)
+	}
+}
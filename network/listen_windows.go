@@ -0,0 +1,15 @@
+package network
+
+import (
+	"errors"
+	"syscall"
+)
+
+// wsaeConnRefused is WSAECONNREFUSED, the errno of a refused connection on Windows, where
+// syscall.ECONNREFUSED is an invented errno which dials never fail with
+const wsaeConnRefused syscall.Errno = 10061
+
+// isConnRefused returns true if err is a refused connection
+func isConnRefused(err error) bool {
+	return errors.Is(err, wsaeConnRefused) || errors.Is(err, syscall.ECONNREFUSED)
+}
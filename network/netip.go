@@ -0,0 +1,41 @@
+package network
+
+import (
+	"net/netip"
+)
+
+// ToAddrPort parses s using ParseAddress, and returns the address component and the port as a
+// netip.AddrPort; the zone (if any) is kept, and an IPv4-mapped IPv6 address is kept in this form
+// (see netip.Addr.Unmap). If there is no port, the port of the returned AddrPort is 0, hence
+// it cannot be told apart from the wildcard port 0
+func ToAddrPort(s string) (ap netip.AddrPort, err error) {
+	var address string
+	var p Port
+	if address, p, err = ParseAddress(s); err != nil {
+		return
+	}
+	var addr netip.Addr
+	if addr, err = netip.ParseAddr(address); err != nil {
+		return
+	}
+	var n uint16
+	if p.IsSet() {
+		n = uint16(p.Uint64())
+	}
+	ap = netip.AddrPortFrom(addr, n)
+	return
+}
+
+// FromAddrPort is the inverse of ToAddrPort, returning the address component (with the zone, if any)
+// and the Port of ap; as in ToAddrPort, port 0 means no port, hence the returned Port is unset.
+// If ap is not valid (e.g. the zero AddrPort), an empty string and an unset Port are returned
+func FromAddrPort(ap netip.AddrPort) (string, Port) {
+	if !ap.IsValid() {
+		return empty, Invalid
+	}
+	var p Port = Invalid
+	if n := ap.Port(); n != 0 {
+		p = port(n)
+	}
+	return ap.Addr().String(), p
+}
@@ -0,0 +1,71 @@
+package network
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestToAddrPort(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  netip.AddrPort
+		valid bool
+	}{
+		{"127.0.0.1:80", netip.MustParseAddrPort("127.0.0.1:80"), true},
+		{"127.0.0.1", netip.MustParseAddrPort("127.0.0.1:0"), true},
+		{"[::1]:443", netip.MustParseAddrPort("[::1]:443"), true},
+		{"::1", netip.MustParseAddrPort("[::1]:0"), true},
+		{"[fe80::1%eth0]:80", netip.MustParseAddrPort("[fe80::1%eth0]:80"), true},
+		// an IPv4-mapped IPv6 address is not unmapped
+		{"[::ffff:192.0.2.1]:80", netip.MustParseAddrPort("[::ffff:192.0.2.1]:80"), true},
+		{"example.com:80", netip.AddrPort{}, false},
+		{"127.0.0.1:65536", netip.AddrPort{}, false},
+		{"", netip.AddrPort{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			ap, err := ToAddrPort(tt.in)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ToAddrPort(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+			if ap != tt.want {
+				t.Errorf("ToAddrPort(%q) = %v, want %v", tt.in, ap, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromAddrPort(t *testing.T) {
+	tests := []struct {
+		ap       netip.AddrPort
+		wantAddr string
+		wantPort Port
+	}{
+		{netip.MustParseAddrPort("127.0.0.1:80"), "127.0.0.1", port(80)},
+		{netip.MustParseAddrPort("[2001:db8::1]:443"), "2001:db8::1", port(443)},
+		{netip.MustParseAddrPort("[fe80::1%eth0]:80"), "fe80::1%eth0", port(80)},
+		{netip.MustParseAddrPort("[::ffff:192.0.2.1]:80"), "::ffff:192.0.2.1", port(80)},
+		// port 0 means no port
+		{netip.MustParseAddrPort("127.0.0.1:0"), "127.0.0.1", Invalid},
+		{netip.AddrPort{}, "", Invalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ap.String(), func(t *testing.T) {
+			addr, p := FromAddrPort(tt.ap)
+			if addr != tt.wantAddr || !p.Equal(tt.wantPort) {
+				t.Errorf("FromAddrPort(%v) = %q, %v, want %q, %v", tt.ap, addr, p, tt.wantAddr, tt.wantPort)
+			}
+		})
+	}
+	// ToAddrPort is the inverse of FromAddrPort
+	for _, s := range []string{"127.0.0.1:80", "[2001:db8::1]:443", "[fe80::1%eth0]:80", "::1"} {
+		ap, err := ToAddrPort(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, p := FromAddrPort(ap)
+		if got := JoinHostPort(addr, p); got != s {
+			t.Errorf("FromAddrPort(ToAddrPort(%q)) = %q", s, got)
+		}
+	}
+}
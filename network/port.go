@@ -1,26 +1,44 @@
 package network
 
 import (
+	"cmp"
 	"fmt"
-	"github.com/densify-dev/net-utils/common"
+	"iter"
+	"math"
+	"reflect"
 	"strconv"
+	"strings"
 )
 
-// portType is unexported to ensure consistency -
-// use the exported consts System, Registered, Dynamic
+// PortType is the type (bucket) of a port - use the exported consts
+// System, Registered, Dynamic; any other value is not a valid PortType
 // see also: https://en.wikipedia.org/wiki/List_of_TCP_and_UDP_port_numbers
-type portType int
+type PortType int
 
 const (
-	System     portType = iota // system or well-known ports
+	System     PortType = iota // system or well-known ports
 	Registered                 // registered ports
 	Dynamic                    // dynamic, private or ephemeral ports
 )
 
+var portTypeNames = map[PortType]string{
+	System:     "system",
+	Registered: "registered",
+	Dynamic:    "dynamic",
+}
+
+// String implements fmt.Stringer, returning the human-readable name of the port type
+func (pt PortType) String() string {
+	if name, ok := portTypeNames[pt]; ok {
+		return name
+	}
+	return fmt.Sprintf("PortType(%d)", int(pt))
+}
+
 // portTypeRange is unexported to ensure consistency (min <= max) -
 // use the exported variables All, NonSystem, NonDynamic
 type portTypeRange struct {
-	min, max portType
+	min, max PortType
 }
 
 // Port is an interface to ensure consistency -
@@ -29,10 +47,15 @@ type portTypeRange struct {
 type Port interface {
 	IsSet() bool
 	IsValid() bool
-	IsValidForType(portType) bool
+	IsValidForType(PortType) bool
 	IsValidForTypeRange(*portTypeRange) bool
+	IsValidForPortRange(PortRange) bool
 	Uint64() uint64
 	Addr(string) string
+	String() string
+	IsWildcard() bool
+	Equal(Port) bool
+	Compare(Port) int
 }
 
 type port uint64
@@ -60,11 +83,71 @@ var All = rangeOf(System, Dynamic)
 var NonSystem = rangeOf(Registered, Dynamic)
 var NonDynamic = rangeOf(System, Registered)
 
+// portRange is an arbitrary numeric range of ports, not necessarily aligned to the port types;
+// it is unexported to ensure consistency (min <= max) - use NewPortRange
 type portRange struct {
 	min, max port
 }
 
-var ranges = map[portType]*portRange{
+// PortRange is implemented by both the port type ranges, e.g. NonSystem, and the arbitrary numeric
+// ranges (see NewPortRange)
+type PortRange interface {
+	Min() Port
+	Max() Port
+	Contains(Port) bool
+	String() string
+}
+
+// NewPortRange returns the range of the ports from min to max (inclusive), e.g. 8000-9000,
+// error if either port is not valid or min is greater than max
+func NewPortRange(min, max Port) (PortRange, error) {
+	pr, err := newPortRange(min, max)
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+func newPortRange(min, max Port) (*portRange, error) {
+	if min == nil || !min.IsValid() || max == nil || !max.IsValid() {
+		return nil, fmt.Errorf("invalid port range %v-%v", min, max)
+	}
+	if min.Uint64() > max.Uint64() {
+		return nil, fmt.Errorf("invalid port range %v-%v: %v > %v", min, max, min, max)
+	}
+	return &portRange{min: port(min.Uint64()), max: port(max.Uint64())}, nil
+}
+
+// Min returns the lowest port of the range
+func (pr *portRange) Min() Port {
+	if pr == nil {
+		return Invalid
+	}
+	return pr.min
+}
+
+// Max returns the highest port of the range
+func (pr *portRange) Max() Port {
+	if pr == nil {
+		return Invalid
+	}
+	return pr.max
+}
+
+// Contains returns true if p is a set port in the range; it is equivalent to p.IsValidForPortRange(pr)
+func (pr *portRange) Contains(p Port) bool {
+	return p != nil && p.IsSet() && p.IsValidForPortRange(pr)
+}
+
+// String implements fmt.Stringer, returning the numeric bounds of the range, e.g. "8000-9000"
+func (pr *portRange) String() string {
+	if pr == nil {
+		return nilRange
+	}
+	return fmt.Sprintf("%d-%d", pr.min, pr.max)
+}
+
+var ranges = map[PortType]*portRange{
 	System:     {min: MinSystem, max: MaxSystem},
 	Registered: {min: MinRegistered, max: MaxRegistered},
 	Dynamic:    {min: MinDynamic, max: MaxDynamic},
@@ -78,14 +161,17 @@ func (p port) IsValid() bool {
 	return p.IsValidForTypeRange(All)
 }
 
-func (p port) IsValidForType(pt portType) bool {
+func (p port) IsValidForType(pt PortType) bool {
 	return p.IsValidForTypeRange(rangeOfSame(pt))
 }
 
 func (p port) IsValidForTypeRange(ptr *portTypeRange) bool {
-	return ptr != nil &&
-		p >= ranges[ptr.min].min &&
-		p <= ranges[ptr.max].max
+	min, max, ok := ptr.bounds()
+	return ok && p >= min && p <= max
+}
+
+func (p port) IsValidForPortRange(pr PortRange) bool {
+	return pr != nil && p.IsSet() && p.Uint64() >= pr.Min().Uint64() && p.Uint64() <= pr.Max().Uint64()
 }
 
 func (p port) Uint64() uint64 {
@@ -94,17 +180,63 @@ func (p port) Uint64() uint64 {
 
 const (
 	hostPortFormat = "%s%s%d"
+	unset          = "<unset>"
+	nilRange       = "<nil>"
+	rangeFormat    = "%s (%d-%d)"
+	rangeSep       = "-"
 )
 
 func (p port) Addr(host string) (addr string) {
 	if p.IsValid() {
-		addr = fmt.Sprintf(hostPortFormat, host, common.Colon, p)
+		addr = fmt.Sprintf(hostPortFormat, host, colon, p)
 	}
 	return
 }
 
+// String implements fmt.Stringer, returning the decimal representation of the port,
+// or "<unset>" if the port is not set
+func (p port) String() string {
+	if !p.IsSet() {
+		return unset
+	}
+	return strconv.FormatUint(uint64(p), 10)
+}
+
+// IsWildcard returns true for port 0, which means "any port" when listening, i.e. the OS chooses
+// a free port (see also FindFreePort); it is not meaningful when connecting
+func (p port) IsWildcard() bool {
+	return p == MinSystem
+}
+
+// Equal returns true if both ports are set and have the same value, or if both are unset
+// (a nil Port is considered unset)
+func (p port) Equal(other Port) bool {
+	return p.Compare(other) == 0
+}
+
+// Compare returns -1, 0 or +1 if p is less than, equal to or greater than other, respectively;
+// set ports are ordered by value, and unset ports (including a nil Port) sort last, equal to each other
+func (p port) Compare(other Port) int {
+	otherSet := other != nil && other.IsSet()
+	switch {
+	case !p.IsSet() && !otherSet:
+		return 0
+	case !p.IsSet():
+		return 1
+	case !otherSet:
+		return -1
+	}
+	return cmp.Compare(p.Uint64(), other.Uint64())
+}
+
+// PortInput is the set of types from which a Port can be obtained; signed integers
+// are checked for negative values, strings are trimmed of surrounding whitespace and then
+// parsed as base-10 unsigned integers, hence " 443 " is valid, while a sign or a base prefix
+// is rejected, e.g. "+80" or "0x50". The port of an address is not trimmed, e.g. ParseAddress rejects
+// "127.0.0.1: 80", as whitespace is not part of an address. Byte slices, e.g. from a buffer or a scanner,
+// are parsed exactly like strings, without being converted to a string unless they are invalid
 type PortInput interface {
-	~uint64 | ~string
+	~int | ~uint16 | ~uint32 | ~uint64 | ~string | ~[]byte
 }
 
 // NewPort returns a Port if the argument has a valid TCP/UDP port number
@@ -115,34 +247,198 @@ func NewPort[PI PortInput](pi PI) (Port, error) {
 
 // NewPortForType returns a Port if the argument has a valid TCP/UDP port number
 // for the requested port type, error otherwise
-func NewPortForType[PI PortInput](pi PI, pt portType) (Port, error) {
+func NewPortForType[PI PortInput](pi PI, pt PortType) (Port, error) {
 	return NewPortForTypeRange(pi, rangeOfSame(pt))
 }
 
 // NewPortForTypeRange returns a Port if the argument has a valid TCP/UDP port number
 // for the requested port type range, error otherwise
-func NewPortForTypeRange[PI PortInput](pi PI, ptr *portTypeRange) (p Port, err error) {
+func NewPortForTypeRange[PI PortInput](pi PI, ptr *portTypeRange) (Port, error) {
+	return newPortForTypeRange(pi, ptr, true)
+}
+
+// newPortForTypeRange behaves like NewPortForTypeRange, only that a string input is trimmed only if trim
+// is set; the address parsers do not trim the port, as whitespace is not part of an address
+func newPortForTypeRange[PI PortInput](pi PI, ptr *portTypeRange, trim bool) (p Port, err error) {
 	var n uint64
-	switch v := any(pi).(type) {
-	case string:
-		n, err = strconv.ParseUint(v, 10, 64)
-	case uint64:
-		n = v
+	// switch on the kind rather than on the type, to support types derived from the PortInput types
+	switch v := reflect.ValueOf(pi); v.Kind() {
+	case reflect.String:
+		n, err = parsePortNumber(v.String(), trim)
+	case reflect.Slice:
+		n, err = parsePortNumber(v.Bytes(), trim)
+	case reflect.Int:
+		if i := v.Int(); i < 0 {
+			err = fmt.Errorf("invalid port %d", i)
+		} else {
+			n = uint64(i)
+		}
+	default:
+		n = v.Uint()
 	}
 	if err == nil {
 		if candidate := port(n); candidate.IsValidForTypeRange(ptr) {
 			p = candidate
 		} else {
-			err = fmt.Errorf("invalid port %d", n)
+			err = fmt.Errorf("invalid port %d: not in range %v", n, ptr)
+		}
+	}
+	return
+}
+
+// parsePortNumber parses the port number of a string or byte slice PortInput; decimal digits are parsed
+// directly, so that a byte slice is converted to a string only to report an error (see parseDigits)
+func parsePortNumber[T string | []byte](s T, trim bool) (n uint64, err error) {
+	t := s
+	if trim {
+		t = trimASCIISpace(t)
+	}
+	var ok bool
+	if n, ok = parseDigits(t); ok {
+		return
+	}
+	// anything else, e.g. a sign, a base prefix, an overflow or non-ASCII whitespace, is left to
+	// strconv.ParseUint, for the same result (and error) as with a string
+	str := string(s)
+	if trim {
+		str = strings.TrimSpace(str)
+	}
+	if n, err = strconv.ParseUint(str, 10, 64); err != nil {
+		err = fmt.Errorf("invalid port %q: %w", string(s), err)
+	}
+	return
+}
+
+// parseDigits returns the value of s if it is a non-empty sequence of decimal digits which fits in
+// an uint64, i.e. exactly when strconv.ParseUint(s, 10, 64) succeeds without a leading sign or prefix
+func parseDigits[T string | []byte](s T) (n uint64, ok bool) {
+	if len(s) == 0 {
+		return
+	}
+	for i := 0; i < len(s); i++ {
+		d := s[i] - '0'
+		if d > 9 || n > (math.MaxUint64-uint64(d))/10 {
+			n = 0
+			return
+		}
+		n = n*10 + uint64(d)
+	}
+	ok = true
+	return
+}
+
+// trimASCIISpace trims the ASCII whitespace surrounding s, which strings.TrimSpace trims as well
+func trimASCIISpace[T string | []byte](s T) T {
+	isSpace := func(c byte) bool {
+		return c == ' ' || (c >= '\t' && c <= '\r')
+	}
+	for len(s) > 0 && isSpace(s[0]) {
+		s = s[1:]
+	}
+	for len(s) > 0 && isSpace(s[len(s)-1]) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// GetPortType returns the port type (System, Registered or Dynamic) of a valid Port,
+// error if the port is unset or invalid
+func GetPortType(p Port) (pt PortType, err error) {
+	if p != nil {
+		for pt = System; pt <= Dynamic; pt++ {
+			if p.IsValidForType(pt) {
+				return
+			}
+		}
+	}
+	pt = System
+	err = fmt.Errorf("invalid port %v", p)
+	return
+}
+
+// Ports returns an iterator over all the ports in the range, in ascending order
+func (ptr *portTypeRange) Ports() iter.Seq[Port] {
+	return func(yield func(Port) bool) {
+		if min, max, ok := ptr.bounds(); ok {
+			for p := min; p <= max; p++ {
+				if !yield(p) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Min returns the lowest port of the range, e.g. NonSystem.Min() is 1024
+func (ptr *portTypeRange) Min() Port {
+	if min, _, ok := ptr.bounds(); ok {
+		return min
+	}
+	return Invalid
+}
+
+// Max returns the highest port of the range, e.g. NonSystem.Max() is 65535
+func (ptr *portTypeRange) Max() Port {
+	if _, max, ok := ptr.bounds(); ok {
+		return max
+	}
+	return Invalid
+}
+
+// String implements fmt.Stringer, returning the names of the port types and the numeric bounds
+// of the range, e.g. "registered-dynamic (1024-65535)" for NonSystem, or "system (0-1023)"
+func (ptr *portTypeRange) String() string {
+	if ptr == nil {
+		return nilRange
+	}
+	name := ptr.min.String()
+	if ptr.max != ptr.min {
+		name += rangeSep + ptr.max.String()
+	}
+	if min, max, ok := ptr.bounds(); ok {
+		name = fmt.Sprintf(rangeFormat, name, min, max)
+	}
+	return name
+}
+
+// Contains returns true if p is a set port in the range; it is equivalent to p.IsValidForTypeRange(ptr)
+func (ptr *portTypeRange) Contains(p Port) bool {
+	return p != nil && p.IsSet() && p.IsValidForTypeRange(ptr)
+}
+
+// Overlaps returns true if the ranges a and b have at least one port in common
+func Overlaps(a, b *portTypeRange) bool {
+	_, ok := Intersect(a, b)
+	return ok
+}
+
+// Intersect returns the range of the ports common to a and b, e.g. Intersect(NonSystem, NonDynamic)
+// is the range of the Registered ports; ok is false if the ranges are disjoint (or invalid)
+func Intersect(a, b *portTypeRange) (ptr *portTypeRange, ok bool) {
+	aMin, aMax, aOk := a.bounds()
+	bMin, bMax, bOk := b.bounds()
+	if ok = aOk && bOk && max(aMin, bMin) <= min(aMax, bMax); ok {
+		// the bounds of both ranges are aligned to port types, and so are those of the intersection
+		ptr = rangeOf(max(a.min, b.min), min(a.max, b.max))
+	}
+	return
+}
+
+// bounds resolves the numeric bounds of the range through the ranges map
+func (ptr *portTypeRange) bounds() (min, max port, ok bool) {
+	if ptr != nil {
+		minRange, maxRange := ranges[ptr.min], ranges[ptr.max]
+		if ok = minRange != nil && maxRange != nil; ok {
+			min, max = minRange.min, maxRange.max
 		}
 	}
 	return
 }
 
-func rangeOfSame(pt portType) *portTypeRange {
+func rangeOfSame(pt PortType) *portTypeRange {
 	return rangeOf(pt, pt)
 }
 
-func rangeOf(min, max portType) *portTypeRange {
+func rangeOf(min, max PortType) *portTypeRange {
 	return &portTypeRange{min: min, max: max}
 }
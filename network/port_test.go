@@ -0,0 +1,186 @@
+package network
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestNewPortWrapsParseError(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantMsg string
+		wantErr error
+	}{
+		{"abc", `invalid port "abc": strconv.ParseUint: parsing "abc": invalid syntax`, strconv.ErrSyntax},
+		{"99999999999999999999", `invalid port "99999999999999999999": strconv.ParseUint: parsing "99999999999999999999": value out of range`, strconv.ErrRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			_, err := NewPort(tt.in)
+			if err == nil {
+				t.Fatal("NewPort() = nil error")
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("NewPort() = %q, want %q", err, tt.wantMsg)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.wantErr)
+			}
+			var numErr *strconv.NumError
+			if !errors.As(err, &numErr) || numErr.Func != "ParseUint" {
+				t.Errorf("errors.As(%v, *strconv.NumError) = false", err)
+			}
+		})
+	}
+}
+
+func TestNewPortEdgeCases(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  Port
+		valid bool
+	}{
+		{"80", port(80), true},
+		{" 80", port(80), true},
+		{" 443 ", port(443), true},
+		{"\t8080\n", port(8080), true},
+		{"+80", nil, false},
+		{"-80", nil, false},
+		{"0x50", nil, false},
+		{"8 0", nil, false},
+		{"", nil, false},
+		{" ", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			p, err := NewPort(tt.in)
+			if (err == nil) != tt.valid {
+				t.Fatalf("NewPort(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+			if tt.valid && !p.Equal(tt.want) {
+				t.Errorf("NewPort(%q) = %v, want %v", tt.in, p, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAddressDoesNotTrimPort(t *testing.T) {
+	for _, s := range []string{"127.0.0.1: 80", "127.0.0.1:80 ", " 127.0.0.1:80", "[::1]: 80"} {
+		if _, _, err := ParseAddress(s); err == nil {
+			t.Errorf("ParseAddress(%q) = nil error", s)
+		}
+	}
+}
+
+func TestPortTypeRangeString(t *testing.T) {
+	tests := []struct {
+		ptr  *portTypeRange
+		want string
+	}{
+		{All, "system-dynamic (0-65535)"},
+		{NonSystem, "registered-dynamic (1024-65535)"},
+		{NonDynamic, "system-registered (0-49151)"},
+		{rangeOfSame(System), "system (0-1023)"},
+		{nil, "<nil>"},
+	}
+	for _, tt := range tests {
+		if got := tt.ptr.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+	if got := port(8080).Addr("127.0.0.1"); got != "127.0.0.1:8080" {
+		t.Errorf("Addr() = %q, want %q", got, "127.0.0.1:8080")
+	}
+}
+
+func TestNewPortBytesMatchesString(t *testing.T) {
+	inputs := []string{
+		"80", "080", "0", "65535", "65536", " 443 ", "\t8080\n", " 80", "80 ",
+		"", " ", "+80", "-80", "0x50", "8_0", "8 0", "abc", "18446744073709551615",
+		"18446744073709551616", "99999999999999999999",
+	}
+	for _, s := range inputs {
+		t.Run(s, func(t *testing.T) {
+			ps, errS := NewPort(s)
+			pb, errB := NewPort([]byte(s))
+			if (errS == nil) != (errB == nil) || (errS != nil && errS.Error() != errB.Error()) {
+				t.Fatalf("NewPort([]byte) = %v, want %v", errB, errS)
+			}
+			if errS == nil && !pb.Equal(ps) {
+				t.Errorf("NewPort([]byte) = %v, want %v", pb, ps)
+			}
+			for _, target := range []error{strconv.ErrSyntax, strconv.ErrRange} {
+				if errors.Is(errS, target) != errors.Is(errB, target) {
+					t.Errorf("errors.Is(%v, %v) differs from the string error %v", errB, target, errS)
+				}
+			}
+			// the port of an address is not trimmed, for both input types
+			_, errS = newPortForTypeRange(s, All, false)
+			_, errB = newPortForTypeRange([]byte(s), All, false)
+			if (errS == nil) != (errB == nil) || (errS != nil && errS.Error() != errB.Error()) {
+				t.Errorf("newPortForTypeRange([]byte, untrimmed) = %v, want %v", errB, errS)
+			}
+		})
+	}
+}
+
+func TestNewPortBytesAllocations(t *testing.T) {
+	s := " 8080 "
+	b := []byte(s)
+	strAllocs := testing.AllocsPerRun(100, func() {
+		_, _ = NewPort(s)
+	})
+	bytesAllocs := testing.AllocsPerRun(100, func() {
+		_, _ = NewPort(b)
+	})
+	if bytesAllocs > strAllocs {
+		t.Errorf("NewPort([]byte) allocates %v times, NewPort(string) %v times", bytesAllocs, strAllocs)
+	}
+}
+
+func TestNewPortRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max Port
+		valid    bool
+	}{
+		{"range", port(8000), port(9000), true},
+		{"single port", port(8080), port(8080), true},
+		{"across port types", port(1000), port(50000), true},
+		{"min above max", port(9000), port(8000), false},
+		{"unset min", Invalid, port(9000), false},
+		{"nil max", port(8000), nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pr PortRange
+			pr, err := NewPortRange(tt.min, tt.max)
+			if (err == nil) != tt.valid {
+				t.Fatalf("NewPortRange(%v, %v) = %v, valid %v", tt.min, tt.max, err, tt.valid)
+			}
+			if !tt.valid {
+				if pr != nil {
+					t.Errorf("NewPortRange(%v, %v) = %v, want nil on error", tt.min, tt.max, pr)
+				}
+				return
+			}
+			if pr.Min().Uint64() != tt.min.Uint64() || pr.Max().Uint64() != tt.max.Uint64() {
+				t.Errorf("NewPortRange(%v, %v) = %v", tt.min, tt.max, pr)
+			}
+			for _, p := range []Port{tt.min, tt.max} {
+				if !p.IsValidForPortRange(pr) || !pr.Contains(p) {
+					t.Errorf("%v is not in %v", p, pr)
+				}
+			}
+			for _, p := range []Port{port(tt.min.Uint64() - 1), port(tt.max.Uint64() + 1), Invalid} {
+				if p.IsValidForPortRange(pr) || pr.Contains(p) {
+					t.Errorf("%v is in %v", p, pr)
+				}
+			}
+		})
+	}
+	if port(80).IsValidForPortRange(nil) {
+		t.Error("IsValidForPortRange(nil) = true")
+	}
+}
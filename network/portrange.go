@@ -0,0 +1,37 @@
+package network
+
+var _ PortRange = (*portTypeRange)(nil)
+var _ PortRange = (*portRange)(nil)
+
+// builtinRanges are the exported port type ranges, returned by NewAlignedPortRange when they match
+var builtinRanges = []*portTypeRange{All, NonSystem, NonDynamic}
+
+// NewAlignedPortRange returns the port type range from min to max if their bounds are aligned to port types,
+// e.g. NonSystem for 1024-65535 (the exported ranges are returned as is), and aligned is true; otherwise,
+// it returns the numeric range (see NewPortRange), and aligned is false
+func NewAlignedPortRange(min, max Port) (pr PortRange, aligned bool, err error) {
+	var numeric *portRange
+	if numeric, err = newPortRange(min, max); err != nil {
+		return
+	}
+	var minType, maxType PortType
+	if minType, err = GetPortType(min); err != nil {
+		return
+	}
+	if maxType, err = GetPortType(max); err != nil {
+		return
+	}
+	if ranges[minType].min == numeric.min && ranges[maxType].max == numeric.max {
+		aligned = true
+		pr = rangeOf(minType, maxType)
+		for _, builtin := range builtinRanges {
+			if builtin.min == minType && builtin.max == maxType {
+				pr = builtin
+				break
+			}
+		}
+	} else {
+		pr = numeric
+	}
+	return
+}
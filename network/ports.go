@@ -0,0 +1,64 @@
+package network
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ParsePortRange parses a contiguous range of ports in the form "min-max", e.g. "3000-3010",
+// or a single port, e.g. "3000", yielding equal min and max. Both ends are validated using
+// NewPort, and min must not be greater than max
+func ParsePortRange(s string) (min, max Port, err error) {
+	lo, hi, isRange := strings.Cut(s, rangeSep)
+	if !isRange {
+		hi = lo
+	}
+	if lo == empty || hi == empty {
+		err = fmt.Errorf("invalid port range '%s': missing port", s)
+		return
+	}
+	var pMin, pMax Port
+	if pMin, err = NewPort(lo); err == nil {
+		if pMax, err = NewPort(hi); err == nil && pMin.Uint64() > pMax.Uint64() {
+			err = fmt.Errorf("%v > %v", pMin, pMax)
+		}
+	}
+	if err == nil {
+		min, max = pMin, pMax
+	} else {
+		err = fmt.Errorf("invalid port range '%s': %w", s, err)
+	}
+	return
+}
+
+// ParsePortList parses a comma-separated list of ports, e.g. "80,443,8080"; whitespace around
+// each element is trimmed, and each element is validated using NewPort. The returned ports keep
+// the order of the input and are not deduplicated. An error identifies the first invalid element
+// and its (zero-based) index
+func ParsePortList(s string) ([]Port, error) {
+	elems := strings.Split(s, comma)
+	ports := make([]Port, 0, len(elems))
+	for i, elem := range elems {
+		p, err := NewPort(strings.TrimSpace(elem))
+		if err != nil {
+			return nil, fmt.Errorf("invalid port list element #%d '%s': %w", i, elem, err)
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// SortPorts sorts ports in place, ascending by value (see Port.Compare); unset ports, including
+// nil ones, sort last. The sort is stable, so equal ports keep their relative order
+func SortPorts(ports []Port) {
+	slices.SortStableFunc(ports, comparePorts)
+}
+
+func comparePorts(a, b Port) int {
+	if a == nil {
+		// nil is unset
+		a = Invalid
+	}
+	return a.Compare(b)
+}
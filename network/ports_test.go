@@ -0,0 +1,66 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestSortPorts(t *testing.T) {
+	ports := []Port{port(8080), Invalid, MaxDynamic, nil, port(443), MinSystem, port(443), MinDynamic}
+	SortPorts(ports)
+	want := []Port{MinSystem, port(443), port(443), port(8080), MinDynamic, MaxDynamic}
+	for i, p := range want {
+		if !ports[i].Equal(p) {
+			t.Errorf("ports[%d] = %v, want %v", i, ports[i], p)
+		}
+	}
+	// the unset ports sort last, keeping their relative order
+	if ports[6] != Invalid || ports[7] != nil {
+		t.Errorf("unset ports = %v, %v, want %v, <nil>", ports[6], ports[7], Invalid)
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		min, max Port
+		valid    bool
+	}{
+		{"80-90", port(80), port(90), true},
+		{"3000", port(3000), port(3000), true},
+		{"90-80", nil, nil, false},
+		{"80-", nil, nil, false},
+		{"-90", nil, nil, false},
+		{"", nil, nil, false},
+		{"80:90", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			min, max, err := ParsePortRange(tt.in)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ParsePortRange(%q) = %v, valid %v", tt.in, err, tt.valid)
+			}
+			if tt.valid && (!min.Equal(tt.min) || !max.Equal(tt.max)) {
+				t.Errorf("ParsePortRange(%q) = %v, %v, want %v, %v", tt.in, min, max, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestParsePortList(t *testing.T) {
+	ports, err := ParsePortList("80, 443,8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Port{port(80), port(443), port(8080)}
+	if len(ports) != len(want) {
+		t.Fatalf("ParsePortList() = %v, want %v", ports, want)
+	}
+	for i, p := range want {
+		if !ports[i].Equal(p) {
+			t.Errorf("ports[%d] = %v, want %v", i, ports[i], p)
+		}
+	}
+	if _, err = ParsePortList("80;443"); err == nil {
+		t.Error("ParsePortList(\"80;443\") = nil error")
+	}
+}
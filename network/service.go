@@ -0,0 +1,105 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	udp = "udp"
+)
+
+// services is an embedded table of well-known service names (per the IANA service name registry),
+// so that lookups do not depend on the presence or content of the system services database
+var services = map[string]map[port]string{
+	tcp: {
+		20:    "ftp-data",
+		21:    "ftp",
+		22:    "ssh",
+		23:    "telnet",
+		25:    "smtp",
+		53:    "domain",
+		80:    "http",
+		88:    "kerberos",
+		110:   "pop3",
+		119:   "nntp",
+		143:   "imap",
+		179:   "bgp",
+		389:   "ldap",
+		443:   "https",
+		445:   "microsoft-ds",
+		465:   "submissions",
+		514:   "shell",
+		587:   "submission",
+		636:   "ldaps",
+		873:   "rsync",
+		993:   "imaps",
+		995:   "pop3s",
+		1433:  "ms-sql-s",
+		2049:  "nfs",
+		3306:  "mysql",
+		3389:  "ms-wbt-server",
+		5432:  "postgresql",
+		5672:  "amqp",
+		6379:  "redis",
+		8080:  "http-alt",
+		27017: "mongodb",
+	},
+	udp: {
+		53:   "domain",
+		67:   "bootps",
+		68:   "bootpc",
+		69:   "tftp",
+		123:  "ntp",
+		137:  "netbios-ns",
+		138:  "netbios-dgm",
+		161:  "snmp",
+		162:  "snmptrap",
+		443:  "https",
+		500:  "isakmp",
+		514:  "syslog",
+		1812: "radius",
+		1813: "radius-acct",
+		2049: "nfs",
+		5353: "mdns",
+	},
+}
+
+// servicePorts is the reverse of services
+var servicePorts = reverseServices()
+
+// LookupServiceName returns the registered service name of the port for the protocol
+// ("tcp" or "udp"), e.g. 443/tcp is "https"; ok is false if there is no such mapping
+func LookupServiceName(p Port, proto string) (name string, ok bool) {
+	if p != nil && p.IsValid() {
+		name, ok = services[strings.ToLower(proto)][port(p.Uint64())]
+	}
+	return
+}
+
+// LookupServicePort returns the port of the service name for the protocol ("tcp" or "udp"),
+// e.g. "https"/tcp is 443; names which are not in the embedded table are looked up
+// using net.LookupPort (i.e. the system services database)
+func LookupServicePort(name, proto string) (Port, error) {
+	proto = strings.ToLower(proto)
+	if p, ok := servicePorts[proto][strings.ToLower(name)]; ok {
+		return p, nil
+	}
+	n, err := net.LookupPort(proto, name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown service '%s/%s': %w", name, proto, err)
+	}
+	return NewPort(n)
+}
+
+func reverseServices() map[string]map[string]port {
+	reversed := make(map[string]map[string]port, len(services))
+	for proto, names := range services {
+		reversed[proto] = make(map[string]port, len(names))
+		for p, name := range names {
+			reversed[proto][name] = p
+		}
+	}
+	return reversed
+}
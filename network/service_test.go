@@ -0,0 +1,68 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestLookupServiceName(t *testing.T) {
+	tests := []struct {
+		p     Port
+		proto string
+		want  string
+		ok    bool
+	}{
+		{port(443), tcp, "https", true},
+		{port(22), "TCP", "ssh", true},
+		{port(53), udp, "domain", true},
+		{port(123), udp, "ntp", true},
+		{port(123), tcp, "", false},
+		{port(1), tcp, "", false},
+		{port(443), "sctp", "", false},
+		{Invalid, tcp, "", false},
+		{nil, tcp, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.proto+"/"+portString(tt.p), func(t *testing.T) {
+			if name, ok := LookupServiceName(tt.p, tt.proto); name != tt.want || ok != tt.ok {
+				t.Errorf("LookupServiceName(%v, %s) = %q, %v, want %q, %v", tt.p, tt.proto, name, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLookupServicePort(t *testing.T) {
+	tests := []struct {
+		name  string
+		proto string
+		want  Port
+		valid bool
+	}{
+		{"https", tcp, port(443), true},
+		{"HTTPS", "Tcp", port(443), true},
+		{"postgresql", tcp, port(5432), true},
+		{"ntp", udp, port(123), true},
+		// a number is a port, per net.LookupPort
+		{"8081", tcp, port(8081), true},
+		{"no-such-service", tcp, nil, false},
+		{"https", "sctp", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.proto+"/"+tt.name, func(t *testing.T) {
+			p, err := LookupServicePort(tt.name, tt.proto)
+			if (err == nil) != tt.valid {
+				t.Fatalf("LookupServicePort(%s, %s) = %v, valid %v", tt.name, tt.proto, err, tt.valid)
+			}
+			if tt.valid && !p.Equal(tt.want) {
+				t.Errorf("LookupServicePort(%s, %s) = %v, want %v", tt.name, tt.proto, p, tt.want)
+			}
+		})
+	}
+}
+
+// portString returns the string of p, which may be nil
+func portString(p Port) string {
+	if p == nil {
+		return "nil"
+	}
+	return p.String()
+}
@@ -0,0 +1,50 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// schemePorts are the default ports of the schemes supported by the DefaultPort option
+var schemePorts = map[string]port{
+	"http":  80,
+	"https": 443,
+}
+
+// ParseURLAddress parses s as a URL, e.g. "http://10.0.0.5:8080/path", and validates its host
+// (the address component and the optional port) like ParseAddress; it returns the scheme (lowercase),
+// the address component and the Port. If the URL has no port and its scheme is "http" or "https",
+// the default port of the scheme (80 or 443) is filled in; for any other scheme, the Port is unset
+func ParseURLAddress(s string) (scheme string, host string, p Port, err error) {
+	return ParseURLAddressWithOptions(s, All, DefaultPort)
+}
+
+// ParseURLAddressWithOptions behaves like ParseURLAddress, only that the host is validated like
+// ParseAddressWithOptions, and the default port is filled in only if opts include DefaultPort
+func ParseURLAddressWithOptions(s string, ptr *portTypeRange, opts ParseOption) (scheme string, host string, p Port, err error) {
+	p = Invalid
+	var u *url.URL
+	if u, err = url.Parse(s); err != nil {
+		err = fmt.Errorf("invalid URL '%s': %w", s, err)
+		return
+	}
+	if u.Host == empty {
+		err = fmt.Errorf("invalid URL '%s': missing host", s)
+		return
+	}
+	var address string
+	var pp Port
+	if address, pp, err = ParseAddressWithOptions(u.Host, ptr, opts); err != nil {
+		err = fmt.Errorf("invalid URL '%s': %w", s, err)
+		return
+	}
+	if dp, ok := schemePorts[u.Scheme]; ok && !pp.IsSet() && opts&DefaultPort != 0 {
+		if !dp.IsValidForTypeRange(ptr) {
+			err = fmt.Errorf("invalid URL '%s': default port %d of scheme %s not in range %v", s, dp, u.Scheme, ptr)
+			return
+		}
+		pp = dp
+	}
+	scheme, host, p = u.Scheme, address, pp
+	return
+}
@@ -0,0 +1,124 @@
+package rhttp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/densify-dev/net-utils/common"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// auth types
+const (
+	BasicAuth  = "basic"
+	BearerAuth = "bearer"
+)
+
+const authorizationHeader = "Authorization"
+
+// AuthConfig is the configuration of the Authorization header added to each attempt of the requests
+// of a client, unless the request has one already. The secret (the password of basic auth, or the token
+// of bearer auth) is set by exactly one of the value itself, a file holding it (trimmed of surrounding
+// whitespace) or an environment variable holding it; it is resolved when the client is constructed.
+// The header is not added to a redirect to another host, so that the secret is not leaked to it
+type AuthConfig struct {
+	// Type is either "basic" or "bearer"
+	Type         string `yaml:"type"`
+	Username     string `yaml:"username,omitempty"`
+	Password     string `yaml:"password,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+	PasswordEnv  string `yaml:"password_env,omitempty"`
+	Token        string `yaml:"token,omitempty"`
+	TokenFile    string `yaml:"token_file,omitempty"`
+	TokenEnv     string `yaml:"token_env,omitempty"`
+}
+
+// Validate checks that ac is complete, and that its secret can be resolved
+func (ac *AuthConfig) Validate() (err error) {
+	_, err = ac.header()
+	return
+}
+
+// header returns the value of the Authorization header per ac (empty if ac is nil)
+func (ac *AuthConfig) header() (string, error) {
+	if ac == nil {
+		return common.Empty, nil
+	}
+	switch strings.ToLower(ac.Type) {
+	case BasicAuth:
+		if ac.Username == common.Empty {
+			return common.Empty, fmt.Errorf("basic auth requires a username")
+		}
+		password, err := secret("password", ac.Password, ac.PasswordFile, ac.PasswordEnv)
+		if err != nil {
+			return common.Empty, err
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(ac.Username+common.Colon+password)), nil
+	case BearerAuth:
+		token, err := secret("token", ac.Token, ac.TokenFile, ac.TokenEnv)
+		if err != nil {
+			return common.Empty, err
+		}
+		return "Bearer " + token, nil
+	default:
+		return common.Empty, fmt.Errorf("invalid auth type %s", ac.Type)
+	}
+}
+
+// secret resolves a secret which is set by exactly one of value, file and env
+func secret(name, value, file, env string) (s string, err error) {
+	set := 0
+	for _, source := range []string{value, file, env} {
+		if source != common.Empty {
+			set++
+		}
+	}
+	if set != 1 {
+		err = fmt.Errorf("exactly one of %s, %s_file and %s_env must be set", name, name, name)
+		return
+	}
+	switch {
+	case file != common.Empty:
+		var b []byte
+		if b, err = os.ReadFile(file); err != nil {
+			err = fmt.Errorf("failed to read %s file: %w", name, err)
+			return
+		}
+		s = strings.TrimSpace(string(b))
+	case env != common.Empty:
+		s = os.Getenv(env)
+	default:
+		s = value
+	}
+	if s == common.Empty {
+		err = fmt.Errorf("empty %s", name)
+	}
+	return
+}
+
+// authTransport adds the Authorization header to each attempt, unless the request has one already;
+// like net/http, which strips the Authorization header on a redirect to another host, it does not add
+// the header to a redirect to a host (including the port) other than that of the original request
+type authTransport struct {
+	rt     http.RoundTripper
+	header string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get(authorizationHeader) == common.Empty && req.URL.Host == originHost(req) {
+		// a RoundTripper must not modify the request
+		req = req.Clone(req.Context())
+		req.Header.Set(authorizationHeader, t.header)
+	}
+	return t.rt.RoundTrip(req)
+}
+
+// originHost returns the host of the original request of req, which differs from that of req
+// if req is a redirect (see http.Request.Response)
+func originHost(req *http.Request) string {
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req.URL.Host
+}
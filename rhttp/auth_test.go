@@ -0,0 +1,46 @@
+package rhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthHeaderNotSentAcrossHosts(t *testing.T) {
+	const token = "secret"
+	var otherAuth, sameAuth string
+	other := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		otherAuth = r.Header.Get(authorizationHeader)
+	}))
+	defer other.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/target", http.StatusFound)
+		case "/target":
+			sameAuth = r.Header.Get(authorizationHeader)
+		default:
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		}
+	}))
+	defer origin.Close()
+	rc := NoRetryConfig()
+	rc.Auth = &AuthConfig{Type: BearerAuth, Token: token}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/same", "/other"} {
+		resp, err := c.Get(origin.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+	if want := "Bearer " + token; sameAuth != want {
+		t.Errorf("Authorization on a redirect to the same host = %q, want %q", sameAuth, want)
+	}
+	if otherAuth != "" {
+		t.Errorf("Authorization on a redirect to another host = %q, want none", otherAuth)
+	}
+}
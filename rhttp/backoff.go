@@ -0,0 +1,266 @@
+package rhttp
+
+import (
+	"errors"
+	"fmt"
+	hrhttp "github.com/hashicorp/go-retryablehttp"
+	"maps"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	retryAfterHeader  = "Retry-After"
+	statusClassSuffix = "xx"
+)
+
+// lockedRand is a source of randomness which is safe for concurrent use,
+// as the Backoff of a client may be called concurrently by multiple requests
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand() *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// newRand returns the source of randomness of a client constructed from rc
+func (rc *RetryConfig) newRand() *lockedRand {
+	if rc.rand != nil {
+		return rc.rand
+	}
+	return newLockedRand()
+}
+
+// int63n returns a random number in [0, n)
+func (lr *lockedRand) int63n(n int64) int64 {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	return lr.r.Int63n(n)
+}
+
+// float64 returns a random number in [0.0, 1.0)
+func (lr *lockedRand) float64() float64 {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	return lr.r.Float64()
+}
+
+// newLinearJitterBackoff returns a Backoff which behaves like retryablehttp's LinearJitterBackoff,
+// only that it uses r: it waits a random duration in [min, max), multiplied by the (one-based) attempt
+func newLinearJitterBackoff(r *lockedRand) hrhttp.Backoff {
+	return func(min, max time.Duration, attemptNum int, _ *http.Response) time.Duration {
+		attempt := time.Duration(attemptNum + 1)
+		if max <= min {
+			return min * attempt
+		}
+		return (min + time.Duration(r.float64()*float64(max-min))) * attempt
+	}
+}
+
+// newConstantJitterBackoff returns a Backoff which waits min plus a random jitter
+// in [0, max - min], regardless of the attempt
+func newConstantJitterBackoff(r *lockedRand) hrhttp.Backoff {
+	return func(min, max time.Duration, _ int, _ *http.Response) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(r.int63n(int64(max-min)+1))
+	}
+}
+
+// newDecorrelatedJitterBackoff returns a Backoff implementing the AWS-style "decorrelated jitter"
+// algorithm: sleep = min(max, random_between(min, prev * 3)), where prev is the previous sleep
+// (initially min) of the request, kept in the requestState attached by RetryTransport. If there is
+// no such state, i.e. the attempt failed without a response or the request was sent directly by
+// the retryablehttp client, prev is kept per client and reset on the first retry of each request,
+// hence concurrent requests of the same client then affect each other's waits
+func newDecorrelatedJitterBackoff(r *lockedRand) hrhttp.Backoff {
+	var mu sync.Mutex
+	var prev time.Duration
+	return func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		if resp != nil && resp.Request != nil {
+			if rs := stateOf(resp.Request.Context()); rs != nil {
+				// the attempts of a request are sequential, hence its state needs no locking
+				rs.prevWait = decorrelatedSleep(r, min, max, rs.prevWait, attemptNum)
+				return rs.prevWait
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		prev = decorrelatedSleep(r, min, max, prev, attemptNum)
+		return prev
+	}
+}
+
+// decorrelatedSleep returns the next sleep of the decorrelated jitter algorithm, given the previous one
+func decorrelatedSleep(r *lockedRand, min, max, prev time.Duration, attemptNum int) time.Duration {
+	if attemptNum == 0 || prev < min {
+		prev = min
+	}
+	sleep := min
+	if upper := prev * 3; upper > min {
+		sleep += time.Duration(r.int63n(int64(upper-min) + 1))
+	}
+	if sleep > max {
+		sleep = max
+	}
+	return sleep
+}
+
+// clientBackoff returns the Backoff of a client constructed from rc, whose jittered policies draw from r
+func (rc *RetryConfig) clientBackoff(r *lockedRand) hrhttp.Backoff {
+	b := rc.backoff(r)
+	if len(rc.statusBackoffs) > 0 {
+		byStatus := make(map[string]hrhttp.Backoff, len(rc.statusBackoffs))
+		for key, nb := range rc.statusBackoffs {
+			byStatus[key] = nb(r)
+		}
+		b = statusPoliciesBackoff(b, byStatus)
+	}
+	if rc.RespectRetryAfter {
+		b = retryAfterBackoff(b)
+	}
+	if rc.MaxElapsed > 0 {
+		b = maxElapsedBackoff(b, rc.MaxElapsed)
+	}
+	return b
+}
+
+// statusPoliciesBackoff returns a Backoff which dispatches on the status code of the response: to the Backoff
+// of the status code, or else of its class (e.g. "5xx"), or else to b
+func statusPoliciesBackoff(b hrhttp.Backoff, byStatus map[string]hrhttp.Backoff) hrhttp.Backoff {
+	return func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		if resp != nil {
+			code := strconv.Itoa(resp.StatusCode)
+			if sb, ok := byStatus[code]; ok {
+				return sb(min, max, attemptNum, resp)
+			}
+			if sb, ok := byStatus[code[:1]+statusClassSuffix]; ok {
+				return sb(min, max, attemptNum, resp)
+			}
+		}
+		return b(min, max, attemptNum, resp)
+	}
+}
+
+// resolveStatusPolicies validates the StatusPolicies of rc, and resolves their backoff policies
+func (rc *RetryConfig) resolveStatusPolicies() error {
+	rc.statusBackoffs = nil
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(rc.StatusPolicies)) {
+		policy := rc.StatusPolicies[key]
+		k := strings.ToLower(key)
+		if !validStatusKey(k) {
+			errs = append(errs, fmt.Errorf("invalid status code or class %s", key))
+			continue
+		}
+		nb := lookupPolicy(policy)
+		if nb == nil {
+			errs = append(errs, fmt.Errorf("invalid backoff policy %s for status %s", policy, key))
+			continue
+		}
+		if rc.statusBackoffs == nil {
+			rc.statusBackoffs = make(map[string]newBackoff, len(rc.StatusPolicies))
+		}
+		rc.statusBackoffs[k] = nb
+	}
+	return errors.Join(errs...)
+}
+
+// validStatusKey returns true for a status code, e.g. "429", or a status class, e.g. "5xx"
+func validStatusKey(k string) bool {
+	if len(k) != 3 || k[0] < '1' || k[0] > '5' {
+		return false
+	}
+	if k[1:] == statusClassSuffix {
+		return true
+	}
+	code, err := strconv.ParseUint(k, 10, 16)
+	return err == nil && validStatusCodes([]int{int(code)}) == nil
+}
+
+// retryAfterBackoff returns a Backoff which defers to the Retry-After header of 429 / 503 responses,
+// capped by max; if there is no such (valid) header, it defers to b
+func retryAfterBackoff(b hrhttp.Backoff) hrhttp.Backoff {
+	return func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		if wait, ok := retryAfter(resp); ok {
+			if wait > max {
+				wait = max
+			}
+			return wait
+		}
+		return b(min, max, attemptNum, resp)
+	}
+}
+
+// maxElapsedBackoff returns a Backoff which caps the waits of b at the time left until maxElapsed has passed
+// since the request has started, so that a retry is not made later than that; it relies on the requestState
+// attached to the request context by RetryTransport, hence the wait after a transport error (without a response)
+// is not capped
+func maxElapsedBackoff(b hrhttp.Backoff, maxElapsed time.Duration) hrhttp.Backoff {
+	return func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		wait := b(min, max, attemptNum, resp)
+		if resp != nil && resp.Request != nil {
+			if rs := stateOf(resp.Request.Context()); rs != nil {
+				if left := maxElapsed - time.Since(rs.start); wait > left {
+					wait = left
+				}
+				if wait < 0 {
+					wait = 0
+				}
+			}
+		}
+		return wait
+	}
+}
+
+// retryAfter parses the Retry-After header, either in seconds or as an HTTP-date
+func retryAfter(resp *http.Response) (wait time.Duration, ok bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return
+	}
+	header := resp.Header.Get(retryAfterHeader)
+	if secs, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if ok = secs >= 0; ok {
+			wait = time.Duration(secs) * time.Second
+		}
+	} else if t, err := http.ParseTime(header); err == nil {
+		ok = true
+		if wait = time.Until(t); wait < 0 {
+			wait = 0
+		}
+	}
+	return
+}
+
+// Schedule returns the waits of a client constructed from rc before each of the first n retries, as
+// computed by its backoff policy (without a response, hence Retry-After does not apply); if rc has not
+// been validated yet (see IsValid), Schedule validates it. For jittered policies, the waits are
+// representative, not exact, as each call yields different ones: Schedule draws from a source of
+// randomness of its own, rather than from Rand, so that it does not shift the waits of the clients
+func (rc *RetryConfig) Schedule(n int) ([]time.Duration, error) {
+	if rc == nil {
+		return nil, fmt.Errorf("nil retry configuration")
+	}
+	if !rc.isValid {
+		if err := rc.Validate(); err != nil {
+			return nil, fmt.Errorf("retry configuration is not valid: %w", err)
+		}
+	}
+	if err := validNonNegativeInt(n, "number of retries"); err != nil {
+		return nil, err
+	}
+	b := rc.clientBackoff(newLockedRand())
+	waits := make([]time.Duration, n)
+	for i := range waits {
+		waits[i] = b(rc.WaitMin, rc.WaitMax, i, nil)
+	}
+	return waits, nil
+}
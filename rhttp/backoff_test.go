@@ -0,0 +1,178 @@
+package rhttp
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConstantJitterBackoffWithinBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max time.Duration
+	}{
+		{"range", 100 * time.Millisecond, 2 * time.Second},
+		{"narrow range", time.Second, time.Second + time.Nanosecond},
+		{"equal bounds", time.Second, time.Second},
+		{"max below min", time.Second, time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newConstantJitterBackoff(&lockedRand{r: rand.New(rand.NewSource(1))})
+			upper := max(tt.min, tt.max)
+			for attempt := 0; attempt < 1000; attempt++ {
+				if wait := b(tt.min, tt.max, attempt, nil); wait < tt.min || wait > upper {
+					t.Fatalf("attempt %d: wait %v not in [%v, %v]", attempt, wait, tt.min, upper)
+				}
+			}
+		})
+	}
+}
+
+func TestRandMakesWaitsReproducible(t *testing.T) {
+	waits := func() []time.Duration {
+		rc := &RetryConfig{
+			WaitMin:     time.Second,
+			WaitMax:     5 * time.Second,
+			MaxAttempts: 10,
+			Policy:      ConstantJitterPolicy,
+			Rand:        rand.New(rand.NewSource(42)),
+		}
+		if err := rc.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		b := rc.clientBackoff(rc.newRand())
+		waits := make([]time.Duration, rc.MaxAttempts)
+		for i := range waits {
+			if waits[i] = b(rc.WaitMin, rc.WaitMax, i, nil); waits[i] < rc.WaitMin || waits[i] > rc.WaitMax {
+				t.Errorf("retry %d: wait %v not in [%v, %v]", i, waits[i], rc.WaitMin, rc.WaitMax)
+			}
+		}
+		return waits
+	}
+	first, second := waits(), waits()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("retry %d: waits %v and %v differ with the same seed", i, first[i], second[i])
+		}
+	}
+}
+
+func TestScheduleDoesNotDrawFromRand(t *testing.T) {
+	newConfig := func() *RetryConfig {
+		rc := &RetryConfig{
+			WaitMin:     time.Second,
+			WaitMax:     5 * time.Second,
+			MaxAttempts: 10,
+			Policy:      ConstantJitterPolicy,
+			Rand:        rand.New(rand.NewSource(42)),
+		}
+		if err := rc.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		return rc
+	}
+	previewed, reference := newConfig(), newConfig()
+	if _, err := previewed.Schedule(previewed.MaxAttempts); err != nil {
+		t.Fatal(err)
+	}
+	b, ref := previewed.clientBackoff(previewed.newRand()), reference.clientBackoff(reference.newRand())
+	for i := 0; i < previewed.MaxAttempts; i++ {
+		if wait, want := b(time.Second, 5*time.Second, i, nil), ref(time.Second, 5*time.Second, i, nil); wait != want {
+			t.Errorf("retry %d: wait %v after Schedule, want %v", i, wait, want)
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoffIsPerRequest(t *testing.T) {
+	const min, max = time.Second, time.Hour
+	b := newDecorrelatedJitterBackoff(&lockedRand{r: rand.New(rand.NewSource(1))})
+	newResponse := func(rs *requestState) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		return &http.Response{Request: req.WithContext(context.WithValue(req.Context(), requestStateKey{}, rs))}
+	}
+	a, c := &requestState{}, &requestState{}
+	respA, respC := newResponse(a), newResponse(c)
+	var prevA time.Duration
+	for attempt := 0; attempt < 10; attempt++ {
+		waitA := b(min, max, attempt, respA)
+		if waitA < min || (attempt > 0 && waitA > 3*prevA) {
+			t.Fatalf("attempt %d: wait %v not in [%v, %v]", attempt, waitA, min, 3*prevA)
+		}
+		prevA = waitA
+		// a new request of the same client must not reset the previous wait of the first one
+		_ = b(min, max, 0, respC)
+		if a.prevWait != prevA {
+			t.Fatalf("attempt %d: previous wait %v reset to %v by another request", attempt, prevA, a.prevWait)
+		}
+	}
+}
+
+func TestStatusPoliciesBackoff(t *testing.T) {
+	rc := &RetryConfig{
+		WaitMin:     time.Second,
+		WaitMax:     time.Minute,
+		MaxAttempts: 3,
+		Policy:      ConstantPolicy,
+		StatusPolicies: map[string]string{
+			"429": ExponentialPolicy,
+			"5XX": JitterPolicy,
+			"503": ConstantPolicy,
+		},
+	}
+	if err := rc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	b := rc.clientBackoff(&lockedRand{r: rand.New(rand.NewSource(1))})
+	const attempt = 2
+	tests := []struct {
+		name string
+		resp *http.Response
+		// the wait, or else its bounds
+		want, min, max time.Duration
+	}{
+		{"status code", &http.Response{StatusCode: http.StatusTooManyRequests}, 4 * time.Second, 0, 0},
+		{"status code over its class", &http.Response{StatusCode: http.StatusServiceUnavailable}, time.Second, 0, 0},
+		{"status class", &http.Response{StatusCode: http.StatusBadGateway}, 0, 3 * time.Second, 3 * time.Minute},
+		{"other status code", &http.Response{StatusCode: http.StatusNotFound}, time.Second, 0, 0},
+		{"transport error", nil, time.Second, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait := b(rc.WaitMin, rc.WaitMax, attempt, tt.resp)
+			if tt.want != 0 && wait != tt.want {
+				t.Errorf("wait = %v, want %v", wait, tt.want)
+			}
+			if tt.want == 0 && (wait < tt.min || wait >= tt.max) {
+				t.Errorf("wait = %v, not in [%v, %v)", wait, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestResolveStatusPoliciesRejects(t *testing.T) {
+	tests := []struct {
+		key    string
+		policy string
+	}{
+		{"600", ConstantPolicy},
+		{"99", ConstantPolicy},
+		{"6xx", ConstantPolicy},
+		{"5x", ConstantPolicy},
+		{"50x", ConstantPolicy},
+		{"abc", ConstantPolicy},
+		{"503", "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+" "+tt.policy, func(t *testing.T) {
+			rc := DefaultRetryConfig()
+			rc.StatusPolicies = map[string]string{tt.key: tt.policy}
+			if err := rc.Validate(); err == nil {
+				t.Errorf("Validate() of status policies %v = nil, want an error", rc.StatusPolicies)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package rhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitBreakerConfig configures the circuit breaker of a client: after FailureThreshold consecutive
+// failed requests, the circuit opens and requests fail fast with a CircuitOpenError for OpenDuration;
+// then the circuit is half-open, letting up to HalfOpenProbes requests through: if they all succeed,
+// the circuit closes, and if any fails, it opens again. A request fails if it fails with an error
+// (excluding the cancellation of its context) or with a retryable status code (RetryableStatusCodes,
+// or else the retryablehttp default ones, i.e. 429 and 5xx except 501), after its retries
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"`
+	OpenDuration     time.Duration `yaml:"open_duration"`
+	// HalfOpenProbes is the number of requests let through when half-open; zero means 1
+	HalfOpenProbes int `yaml:"half_open_probes,omitempty"`
+}
+
+// Validate checks that the failure threshold and the open duration of cb are positive,
+// and that its half-open probes are not negative
+func (cb *CircuitBreakerConfig) Validate() (err error) {
+	if cb != nil {
+		var errs []error
+		if cb.FailureThreshold <= 0 {
+			errs = append(errs, fmt.Errorf("circuit breaker failure threshold %d must be positive", cb.FailureThreshold))
+		}
+		if cb.OpenDuration <= 0 {
+			errs = append(errs, fmt.Errorf("circuit breaker open duration %v must be positive", cb.OpenDuration))
+		}
+		errs = append(errs, validNonNegativeInt(cb.HalfOpenProbes, "circuit breaker half-open probes"))
+		err = errors.Join(errs...)
+	}
+	return
+}
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // requests are let through
+	CircuitOpen                         // requests fail fast
+	CircuitHalfOpen                     // probe requests are let through
+)
+
+var circuitStateNames = map[CircuitState]string{
+	CircuitClosed:   "closed",
+	CircuitOpen:     "open",
+	CircuitHalfOpen: "half-open",
+}
+
+// String implements fmt.Stringer, returning the name of the circuit state
+func (cs CircuitState) String() string {
+	if name, ok := circuitStateNames[cs]; ok {
+		return name
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(cs))
+}
+
+// CircuitOpenError is returned by the client returned by NewClient, without sending the request,
+// when its circuit breaker is open (or half-open, with all its probes in flight)
+type CircuitOpenError struct {
+	// Until is the time at which the circuit becomes half-open
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open until %s", e.Until.Format(time.RFC3339))
+}
+
+// circuitBreaker is the circuit breaker of a client, shared by all its requests
+type circuitBreaker struct {
+	mu        sync.Mutex
+	cfg       CircuitBreakerConfig
+	codes     map[int]bool
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+	// now is time.Now, except in tests
+	now func() time.Time
+}
+
+func newCircuitBreaker(cfg CircuitBreakerConfig, statusCodes []int) *circuitBreaker {
+	cfg.HalfOpenProbes = max(cfg.HalfOpenProbes, 1)
+	cb := &circuitBreaker{cfg: cfg, now: time.Now}
+	if len(statusCodes) > 0 {
+		cb.codes = make(map[int]bool, len(statusCodes))
+		for _, code := range statusCodes {
+			cb.codes[code] = true
+		}
+	}
+	return cb
+}
+
+// allow is called before each request, returning a CircuitOpenError if the request must fail fast
+func (cb *circuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh()
+	switch {
+	case cb.state == CircuitOpen:
+	case cb.state == CircuitHalfOpen && cb.probes >= cb.cfg.HalfOpenProbes:
+	default:
+		if cb.state == CircuitHalfOpen {
+			cb.probes++
+		}
+		return nil
+	}
+	return &CircuitOpenError{Until: cb.openedAt.Add(cb.cfg.OpenDuration)}
+}
+
+// record is called with the outcome of each request let through by allow
+func (cb *circuitBreaker) record(resp *http.Response, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// the request has been given up by the caller, which says nothing about the upstream
+		cb.mu.Lock()
+		defer cb.mu.Unlock()
+		if cb.state == CircuitHalfOpen && cb.probes > 0 {
+			cb.probes--
+		}
+		return
+	}
+	failed := err != nil || cb.isFailure(resp.StatusCode)
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case CircuitClosed:
+		if !failed {
+			cb.failures = 0
+		} else if cb.failures++; cb.failures >= cb.cfg.FailureThreshold {
+			cb.open()
+		}
+	case CircuitHalfOpen:
+		if failed {
+			cb.open()
+		} else if cb.successes++; cb.successes >= cb.cfg.HalfOpenProbes {
+			cb.state, cb.failures = CircuitClosed, 0
+		}
+	}
+	// when open, the outcomes of the requests let through before opening are ignored
+}
+
+// isFailure returns true for the retryable status codes
+func (cb *circuitBreaker) isFailure(code int) bool {
+	if cb.codes != nil {
+		return cb.codes[code]
+	}
+	return code == http.StatusTooManyRequests || code == 0 ||
+		(code >= http.StatusInternalServerError && code != http.StatusNotImplemented)
+}
+
+// open must be called with the mutex locked
+func (cb *circuitBreaker) open() {
+	cb.state, cb.openedAt = CircuitOpen, cb.now()
+}
+
+// refresh turns an open circuit half-open once its open duration has passed; it must be called
+// with the mutex locked
+func (cb *circuitBreaker) refresh() {
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.cfg.OpenDuration {
+		cb.state, cb.probes, cb.successes = CircuitHalfOpen, 0, 0
+	}
+}
+
+func (cb *circuitBreaker) currentState() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh()
+	return cb.state
+}
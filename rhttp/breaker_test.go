@@ -0,0 +1,229 @@
+package rhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is the clock of a circuit breaker under test
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestBreaker(cfg CircuitBreakerConfig, statusCodes []int) (*circuitBreaker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cb := newCircuitBreaker(cfg, statusCodes)
+	cb.now = clock.now
+	return cb, clock
+}
+
+// outcome is the outcome of a request recorded by a circuit breaker
+type outcome struct {
+	code int
+	err  error
+}
+
+var (
+	ok200    = outcome{code: http.StatusOK}
+	fail503  = outcome{code: http.StatusServiceUnavailable}
+	failErr  = outcome{err: errors.New("connection refused")}
+	cancel   = outcome{err: context.Canceled}
+	notFound = outcome{code: http.StatusNotFound}
+)
+
+// send lets a request through cb, recording its outcome, and returns the error of allow
+func (o outcome) send(cb *circuitBreaker) error {
+	if err := cb.allow(); err != nil {
+		return err
+	}
+	var resp *http.Response
+	if o.err == nil {
+		resp = &http.Response{StatusCode: o.code}
+	}
+	cb.record(resp, o.err)
+	return nil
+}
+
+func TestCircuitBreakerThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCodes []int
+		outcomes    []outcome
+		want        CircuitState
+	}{
+		{"below threshold", nil, []outcome{fail503, failErr}, CircuitClosed},
+		{"at threshold", nil, []outcome{fail503, failErr, fail503}, CircuitOpen},
+		{"success resets", nil, []outcome{fail503, fail503, ok200, fail503, fail503}, CircuitClosed},
+		{"not retryable", nil, []outcome{notFound, notFound, {code: http.StatusNotImplemented}}, CircuitClosed},
+		{"too many requests", nil, []outcome{{code: http.StatusTooManyRequests}, fail503, fail503}, CircuitOpen},
+		{"cancellation ignored", nil, []outcome{fail503, cancel, fail503, cancel}, CircuitClosed},
+		{"custom status codes", []int{http.StatusNotFound}, []outcome{notFound, notFound, fail503, notFound}, CircuitClosed},
+		{"custom status codes reached", []int{http.StatusNotFound}, []outcome{fail503, notFound, notFound, notFound}, CircuitOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, _ := newTestBreaker(CircuitBreakerConfig{FailureThreshold: 3, OpenDuration: time.Minute}, tt.statusCodes)
+			for i, o := range tt.outcomes {
+				if err := o.send(cb); err != nil {
+					t.Fatalf("request %d: allow() = %v", i, err)
+				}
+			}
+			if got := cb.currentState(); got != tt.want {
+				t.Errorf("state = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerFailsFastUntilHalfOpen(t *testing.T) {
+	const openDuration = 30 * time.Second
+	cb, clock := newTestBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: openDuration}, nil)
+	openedAt := clock.now()
+	if err := fail503.send(cb); err != nil {
+		t.Fatal(err)
+	}
+	wantUntil := openedAt.Add(openDuration)
+	for _, elapsed := range []time.Duration{0, time.Second, openDuration - time.Nanosecond} {
+		clock.t = openedAt.Add(elapsed)
+		var openErr *CircuitOpenError
+		if err := cb.allow(); !errors.As(err, &openErr) {
+			t.Fatalf("after %v: allow() = %v, want a CircuitOpenError", elapsed, err)
+		} else if !openErr.Until.Equal(wantUntil) {
+			t.Errorf("after %v: Until = %v, want %v", elapsed, openErr.Until, wantUntil)
+		}
+	}
+	clock.t = openedAt.Add(openDuration)
+	if got := cb.currentState(); got != CircuitHalfOpen {
+		t.Errorf("state after the open duration = %v, want %v", got, CircuitHalfOpen)
+	}
+	if err := cb.allow(); err != nil {
+		t.Errorf("allow() when half-open = %v", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes int
+		// the outcomes of the probes, sent one after the other
+		outcomes []outcome
+		want     CircuitState
+	}{
+		{"probe succeeds", 0, []outcome{ok200}, CircuitClosed},
+		{"probe fails", 0, []outcome{fail503}, CircuitOpen},
+		{"all probes succeed", 3, []outcome{ok200, ok200, ok200}, CircuitClosed},
+		{"some probes succeed", 3, []outcome{ok200, ok200}, CircuitHalfOpen},
+		{"last probe fails", 3, []outcome{ok200, ok200, failErr}, CircuitOpen},
+		{"cancelled probe", 1, []outcome{cancel}, CircuitHalfOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute, HalfOpenProbes: tt.probes}
+			cb, clock := newTestBreaker(cfg, nil)
+			if err := fail503.send(cb); err != nil {
+				t.Fatal(err)
+			}
+			clock.advance(time.Minute)
+			for i, o := range tt.outcomes {
+				if err := o.send(cb); err != nil {
+					t.Fatalf("probe %d: allow() = %v", i, err)
+				}
+			}
+			if got := cb.currentState(); got != tt.want {
+				t.Errorf("state = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerProbeLimit(t *testing.T) {
+	cb, clock := newTestBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute, HalfOpenProbes: 2}, nil)
+	if err := fail503.send(cb); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(time.Minute)
+	// two probes in flight
+	for i := 0; i < 2; i++ {
+		if err := cb.allow(); err != nil {
+			t.Fatalf("probe %d: allow() = %v", i, err)
+		}
+	}
+	var openErr *CircuitOpenError
+	if err := cb.allow(); !errors.As(err, &openErr) {
+		t.Fatalf("allow() with all the probes in flight = %v, want a CircuitOpenError", err)
+	}
+	// a cancelled probe releases its slot
+	cb.record(nil, context.Canceled)
+	if err := cb.allow(); err != nil {
+		t.Fatalf("allow() after a cancelled probe = %v", err)
+	}
+	if err := cb.allow(); !errors.As(err, &openErr) {
+		t.Fatalf("allow() with all the probes in flight = %v, want a CircuitOpenError", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	cb, clock := newTestBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute}, nil)
+	if err := fail503.send(cb); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(90 * time.Second)
+	reopenedAt := clock.now()
+	if err := failErr.send(cb); err != nil {
+		t.Fatal(err)
+	}
+	var openErr *CircuitOpenError
+	if err := cb.allow(); !errors.As(err, &openErr) {
+		t.Fatalf("allow() after a failed probe = %v, want a CircuitOpenError", err)
+	}
+	if want := reopenedAt.Add(time.Minute); !openErr.Until.Equal(want) {
+		t.Errorf("Until = %v, want %v", openErr.Until, want)
+	}
+}
+
+func TestCoalescedRequestsRecordOnce(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// hold the request, so that the identical ones are coalesced with it
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	rc := &RetryConfig{
+		WaitMin:        time.Millisecond,
+		WaitMax:        time.Millisecond,
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute},
+		Coalesce:       true,
+	}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, err := c.Get(srv.URL); err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	// the coalesced requests are a single failure, below the threshold
+	if cs, _ := c.Transport.(*RetryTransport).CircuitState(); cs != CircuitClosed {
+		t.Errorf("state = %v, want %v", cs, CircuitClosed)
+	}
+}
@@ -0,0 +1,88 @@
+package rhttp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RetryBudget limits the retries across all the requests of a client (see also gRPC retry throttling),
+// using a bucket of up to MaxTokens tokens, which is initially full: each request (excluding its
+// retries) deposits Ratio tokens, e.g. 0.1 allows about one retry per ten requests, the bucket
+// is refilled by RefillRate tokens per second, and each retry withdraws one token. When the bucket
+// has less than one token, requests proceed without retries until it is refilled
+type RetryBudget struct {
+	Ratio      float64 `yaml:"ratio"`
+	RefillRate float64 `yaml:"refill_rate,omitempty"`
+	MaxTokens  float64 `yaml:"max_tokens"`
+}
+
+// BudgetState is a snapshot of the retry budget of a client, for monitoring
+type BudgetState struct {
+	Tokens    float64
+	MaxTokens float64
+}
+
+// Validate validates rb; a nil rb (no retry budget) is valid
+func (rb *RetryBudget) Validate() (err error) {
+	if rb != nil {
+		var errs []error
+		if rb.Ratio < 0 {
+			errs = append(errs, fmt.Errorf("retry budget ratio %v must not be negative", rb.Ratio))
+		}
+		if rb.RefillRate < 0 {
+			errs = append(errs, fmt.Errorf("retry budget refill rate %v must not be negative", rb.RefillRate))
+		}
+		if rb.MaxTokens < 1 {
+			errs = append(errs, fmt.Errorf("retry budget max tokens %v must be at least 1", rb.MaxTokens))
+		}
+		err = errors.Join(errs...)
+	}
+	return
+}
+
+// retryBudget is the retry budget of a client, shared by all its requests
+type retryBudget struct {
+	mu     sync.Mutex
+	cfg    RetryBudget
+	tokens float64
+	last   time.Time
+}
+
+func newRetryBudget(cfg RetryBudget) *retryBudget {
+	return &retryBudget{cfg: cfg, tokens: cfg.MaxTokens, last: time.Now()}
+}
+
+// add adds n (possibly negative) tokens, on top of the refill since the last call; it must be called
+// with the mutex locked
+func (b *retryBudget) add(n float64) {
+	now := time.Now()
+	b.tokens = min(b.tokens+n+b.cfg.RefillRate*now.Sub(b.last).Seconds(), b.cfg.MaxTokens)
+	b.last = now
+}
+
+// deposit is called for each request
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.add(b.cfg.Ratio)
+}
+
+// withdraw is called for each retry, returning false if the budget is exhausted
+func (b *retryBudget) withdraw() (ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.add(0)
+	if ok = b.tokens >= 1; ok {
+		b.tokens--
+	}
+	return
+}
+
+func (b *retryBudget) state() BudgetState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.add(0)
+	return BudgetState{Tokens: b.tokens, MaxTokens: b.cfg.MaxTokens}
+}
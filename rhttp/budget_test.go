@@ -0,0 +1,79 @@
+package rhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetWithdrawsOnlyForRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	rc := &RetryConfig{
+		WaitMin:     time.Millisecond,
+		WaitMax:     time.Millisecond,
+		MaxAttempts: 1,
+		Budget:      &RetryBudget{MaxTokens: 10},
+	}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if resp, err := c.Get(srv.URL); err == nil {
+			_ = resp.Body.Close()
+		}
+		// each request is retried once, the last attempt withdraws no token
+		bs, ok := c.Transport.(*RetryTransport).Budget()
+		if !ok {
+			t.Fatal("Budget() ok = false")
+		}
+		if want := float64(10 - i); bs.Tokens != want {
+			t.Errorf("after %d request(s): tokens = %v, want %v", i, bs.Tokens, want)
+		}
+	}
+}
+
+func TestCoalescedRequestsDepositOnce(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		// hold the request, so that the identical ones are coalesced with it
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+	rc := &RetryConfig{
+		WaitMin:     time.Millisecond,
+		WaitMax:     time.Millisecond,
+		MaxAttempts: 1,
+		Budget:      &RetryBudget{Ratio: 0.5, MaxTokens: 10},
+		Coalesce:    true,
+	}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each failed request deposits 0.5 tokens (up to MaxTokens) and withdraws 1 for its retry: 7.5 are left
+	for i := 0; i < 4; i++ {
+		if resp, err := c.Get(srv.URL); err == nil {
+			_ = resp.Body.Close()
+		}
+	}
+	failing.Store(false)
+	_ = sendConcurrently(t, c, 5, func(int) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		return req
+	})
+	bs, _ := c.Transport.(*RetryTransport).Budget()
+	if want := 8.0; bs.Tokens != want {
+		t.Errorf("tokens = %v, want %v (a single deposit for the coalesced requests)", bs.Tokens, want)
+	}
+}
@@ -1,85 +1,403 @@
 package rhttp
 
 import (
+	"errors"
 	"fmt"
 	"github.com/densify-dev/net-utils/common"
 	hrhttp "github.com/hashicorp/go-retryablehttp"
+	"maps"
+	"math/rand"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
 // policies
 const (
-	DefaultPolicy     = "default"
-	ExponentialPolicy = "exponential"
-	JitterPolicy      = "jitter"
-	ConstantPolicy    = "const"
+	DefaultPolicy        = "default"
+	ExponentialPolicy    = "exponential"
+	JitterPolicy         = "jitter"
+	ConstantPolicy       = "const"
+	ConstantJitterPolicy = "const_jitter"
+	DecorrelatedPolicy   = "decorrelated"
 )
 
+// ConstantBackoff always waits exactly min (i.e. the WaitMin of the RetryConfig),
+// regardless of the attempt; see ConstantJitterPolicy for a jittered alternative. Its signature is that
+// of retryablehttp's Backoff, hence the ignored parameters
 func ConstantBackoff(min, _ time.Duration, _ int, _ *http.Response) time.Duration {
 	return min
 }
 
-var policies = map[string]hrhttp.Backoff{
-	common.Empty:      hrhttp.DefaultBackoff,
-	DefaultPolicy:     hrhttp.DefaultBackoff,
-	ExponentialPolicy: hrhttp.DefaultBackoff,
-	JitterPolicy:      hrhttp.LinearJitterBackoff,
-	ConstantPolicy:    ConstantBackoff,
+// newBackoff constructs a Backoff using the source of randomness r (if it is jittered); it is called
+// once per client, so that stateful policies do not share their state across clients
+type newBackoff func(r *lockedRand) hrhttp.Backoff
+
+var policies = map[string]newBackoff{
+	common.Empty:         stateless(hrhttp.DefaultBackoff),
+	DefaultPolicy:        stateless(hrhttp.DefaultBackoff),
+	ExponentialPolicy:    stateless(hrhttp.DefaultBackoff),
+	JitterPolicy:         newLinearJitterBackoff,
+	ConstantPolicy:       stateless(ConstantBackoff),
+	ConstantJitterPolicy: newConstantJitterBackoff,
+	DecorrelatedPolicy:   newDecorrelatedJitterBackoff,
+}
+
+func stateless(b hrhttp.Backoff) newBackoff {
+	return func(_ *lockedRand) hrhttp.Backoff {
+		return b
+	}
+}
+
+var policiesMu sync.RWMutex
+
+// RegisterPolicy registers a custom backoff policy, so that its name can be used as the Policy
+// of a RetryConfig; policy names are case-insensitive, and must be neither empty nor
+// already registered (in particular, the built-in policies cannot be overridden)
+func RegisterPolicy(name string, b hrhttp.Backoff) error {
+	key := strings.ToLower(name)
+	if key == common.Empty {
+		return fmt.Errorf("empty backoff policy name")
+	}
+	if b == nil {
+		return fmt.Errorf("nil backoff for policy %s", name)
+	}
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	if _, exists := policies[key]; exists {
+		return fmt.Errorf("backoff policy %s is already registered", name)
+	}
+	policies[key] = stateless(b)
+	return nil
+}
+
+func lookupPolicy(name string) newBackoff {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	return policies[strings.ToLower(name)]
+}
+
+// PolicyByName returns a new Backoff of the backoff policy name (case-insensitive, and empty for
+// DefaultPolicy), e.g. for a retryablehttp client which is not constructed from a RetryConfig;
+// ok is false if there is no such policy. A jittered policy has its own source of randomness
+func PolicyByName(name string) (b hrhttp.Backoff, ok bool) {
+	nb := lookupPolicy(name)
+	if ok = nb != nil; ok {
+		b = nb(newLockedRand())
+	}
+	return
+}
+
+// SupportedPolicies returns the names of the backoff policies, built-in and registered (see RegisterPolicy),
+// sorted; the empty name, which stands for DefaultPolicy, is not included
+func SupportedPolicies() []string {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		if name != common.Empty {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
 }
 
 type RetryConfig struct {
-	WaitMin     time.Duration  `yaml:"wait_min"`
-	WaitMax     time.Duration  `yaml:"wait_max"`
-	MaxAttempts int            `yaml:"max_attempts"`
-	Policy      string         `yaml:"policy,omitempty"`
-	backoff     hrhttp.Backoff `yaml:"-"`
-	isValid     bool           `yaml:"-"`
+	WaitMin     time.Duration `yaml:"wait_min"`
+	WaitMax     time.Duration `yaml:"wait_max"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	Policy      string        `yaml:"policy,omitempty"`
+	// StatusPolicies, if set, maps status codes (e.g. "429") and classes (e.g. "5xx") to backoff policies,
+	// which override Policy for the responses with these status codes; a status code takes precedence
+	// over its class. Policy applies to any other response, and to transport errors
+	StatusPolicies map[string]string `yaml:"status_policies,omitempty"`
+	// WaitFloor, if positive, is the lowest WaitMin allowed by Validate (e.g. 1ms), to protect against
+	// a tiny WaitMin which turns retries into a busy loop; zero (the default) disables the check
+	WaitFloor time.Duration `yaml:"wait_floor,omitempty"`
+	// RespectRetryAfter makes the client wait as instructed by the Retry-After header of 429 / 503
+	// responses (capped by WaitMax) regardless of the policy; note that the default / exponential
+	// policies honour the header anyway, uncapped
+	RespectRetryAfter bool `yaml:"respect_retry_after,omitempty"`
+	// RetryableStatusCodes, if set, are the exact response status codes which are retried
+	// (in addition to transport errors), instead of the default 429 and 5xx except 501
+	RetryableStatusCodes []int `yaml:"retryable_status_codes,omitempty"`
+	// MaxElapsed, if positive, caps the total time of a request across all its attempts: once it
+	// has passed, no further retries are made even if attempts remain, and the wait before a retry
+	// is capped at the time left. After a transport error (without a response), the wait is not capped,
+	// hence a request may exceed MaxElapsed by up to WaitMax, besides the time of its last attempt;
+	// zero means unlimited
+	MaxElapsed time.Duration `yaml:"max_elapsed,omitempty"`
+	// RequestLogHook, if set, is called before each attempt, including retries (the attempt number
+	// is zero-based); it is set programmatically, not unmarshalled
+	RequestLogHook hrhttp.RequestLogHook `yaml:"-"`
+	// ResponseLogHook, if set, is called with the response of each attempt, including retries,
+	// unless the attempt failed without a response; it is set programmatically, not unmarshalled
+	ResponseLogHook hrhttp.ResponseLogHook `yaml:"-"`
+	// Observer, if set, observes every attempt; it is set programmatically, not unmarshalled
+	Observer AttemptObserver `yaml:"-"`
+	// TLS, if set, is used to build the transport of the client, if NewClient is passed a nil RoundTripper
+	TLS *TLSConfig `yaml:"tls,omitempty"`
+	// ProxyURL, if set, is the proxy of the transport of the client, if NewClient is passed a nil
+	// RoundTripper; NoProxy lists the exceptions (per the NO_PROXY environment variable format,
+	// which is used if NoProxy is empty). If ProxyURL is empty, the proxy is taken from the environment
+	ProxyURL string `yaml:"proxy_url,omitempty"`
+	NoProxy  string `yaml:"no_proxy,omitempty"`
+	// Transport, if set, tunes the transport of the client, if NewClient is passed a nil RoundTripper
+	Transport *TransportConfig `yaml:"transport,omitempty"`
+	// Auth, if set, adds the Authorization header to each attempt of the requests of the client
+	Auth *AuthConfig `yaml:"auth,omitempty"`
+	// RateLimit, if set, limits the rate of the attempts of the requests of the client
+	RateLimit *RateLimitConfig `yaml:"rate_limit,omitempty"`
+	// Timeout, if positive, is the timeout of the client returned by NewClient (see http.Client.Timeout),
+	// hence it bounds the whole operation, including all attempts and the waits between them;
+	// zero means no timeout
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// IdempotentOnly prevents retrying POST / PATCH requests (which may have duplicate side effects),
+	// unless they carry an Idempotency-Key header; requests with any other method are retried as usual
+	IdempotentOnly bool `yaml:"idempotent_only,omitempty"`
+	// Budget, if set, limits the retries across all the requests of the client returned by NewClient;
+	// its current state is available via the Budget method of the client transport (see RetryTransport)
+	Budget *RetryBudget `yaml:"budget,omitempty"`
+	// MaxDrainBytes, if positive, caps the bytes read from the body of a response to be retried (including
+	// by the ResponseLogHook), which retryablehttp drains so that the connection can be reused; if the body
+	// is longer, the connection is closed instead. Zero means the retryablehttp limit (4 KiB)
+	MaxDrainBytes int `yaml:"max_drain_bytes,omitempty"`
+	// CircuitBreaker, if set, makes the client returned by NewClient fail fast with a CircuitOpenError while
+	// the upstream is failing; its current state is available via the CircuitState method of the client
+	// transport (see RetryTransport)
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker,omitempty"`
+	// Coalesce makes the client returned by NewClient dedup in-flight identical GET / HEAD requests
+	// (without a body), keyed by method, URL and Accept* headers: while a request is in flight, identical
+	// requests wait for its response instead of being sent, and they are counted once by the Budget
+	// and the CircuitBreaker. Requests with credentials (Authorization, Proxy-Authorization, Cookie) or
+	// a Range are never coalesced. Note the caveats: the response may be stale for requests which arrive
+	// while it is in flight, and the cancellation of the request in flight fails the identical requests too.
+	// As the response is shared, its body is read into memory, and each request gets a copy;
+	// a response with a body longer than 1 MiB is not shared, the identical requests are sent instead
+	Coalesce bool `yaml:"coalesce,omitempty"`
+	// WrapTransport, if set, wraps the transport of the attempts (rt passed to NewClient, or else the one built
+	// by NewTransport), e.g. to trace each attempt with OpenTelemetry, without this
+	// module depending on it: func(rt http.RoundTripper) http.RoundTripper { return otelhttp.NewTransport(rt) }.
+	// As each attempt is sent with the context of the request, the span of each attempt is a child of
+	// the span in the request context, and the trace context is propagated by each attempt, including retries.
+	// It is set programmatically, not unmarshalled
+	WrapTransport func(http.RoundTripper) http.RoundTripper `yaml:"-"`
+	// Rand, if set, is the source of randomness of the jittered policies, making their waits reproducible,
+	// e.g. in tests; it is shared by all the clients constructed from rc, guarded by a mutex, hence it must
+	// not be used elsewhere. If nil, each client has its own source, seeded from the time
+	Rand    *rand.Rand  `yaml:"-"`
+	backoff newBackoff  `yaml:"-"`
+	rand    *lockedRand `yaml:"-"`
+	// the backoff policies of StatusPolicies, keyed by the lowercase status code / class
+	statusBackoffs map[string]newBackoff `yaml:"-"`
+	isValid        bool                  `yaml:"-"`
 }
 
-// Validate must be called once, after rc has been constructed / unmarshalled
+// recommended defaults, the same as retryablehttp's
+const (
+	defaultWaitMin     = time.Second
+	defaultWaitMax     = 30 * time.Second
+	defaultMaxAttempts = 4
+)
+
+// DefaultRetryConfig returns a validated RetryConfig with the recommended values:
+// WaitMin 1s, WaitMax 30s, MaxAttempts 4 and the exponential policy; if any of its fields
+// is modified, it must be validated again
+func DefaultRetryConfig() *RetryConfig {
+	rc := &RetryConfig{
+		WaitMin:     defaultWaitMin,
+		WaitMax:     defaultWaitMax,
+		MaxAttempts: defaultMaxAttempts,
+		Policy:      ExponentialPolicy,
+	}
+	_ = rc.Validate()
+	return rc
+}
+
+// NoRetryConfig returns a validated RetryConfig which makes exactly one attempt, without retries,
+// e.g. for a client which goes through the same construction path (logging, transport) as retrying ones;
+// MaxAttempts is the maximum number of retries after the first attempt (see retryablehttp's RetryMax),
+// hence it is zero
+func NoRetryConfig() *RetryConfig {
+	rc := DefaultRetryConfig()
+	rc.MaxAttempts = 0
+	_ = rc.Validate()
+	return rc
+}
+
+// Validate must be called once, after rc has been constructed / unmarshalled;
+// it reports all the issues found, joined (see errors.Join)
 func (rc *RetryConfig) Validate() (err error) {
 	if rc != nil {
-		if err = validDurations(0, rc.WaitMin, false); err == nil {
-			if err = validDurations(rc.WaitMin, rc.WaitMin, true); err == nil {
-				if err = validPositive(rc.MaxAttempts); err == nil {
-					if rc.backoff = policies[strings.ToLower(rc.Policy)]; rc.backoff == nil {
-						err = fmt.Errorf("invalid backoff policy %s", rc.Policy)
-					}
-				}
-			}
+		var policyErr error
+		if rc.Rand != nil {
+			rc.rand = &lockedRand{r: rc.Rand}
+		} else {
+			rc.rand = nil
 		}
+		if rc.backoff = lookupPolicy(rc.Policy); rc.backoff == nil {
+			policyErr = fmt.Errorf("invalid backoff policy %s", rc.Policy)
+		}
+		err = errors.Join(
+			validDurations(0, rc.WaitMin, false),
+			validDurations(rc.WaitMin, rc.WaitMax, true),
+			validNonNegative(rc.WaitFloor, "wait floor"),
+			validWaitFloor(rc.WaitMin, rc.WaitFloor),
+			validNonNegativeInt(rc.MaxAttempts, "max attempts"),
+			policyErr,
+			rc.resolveStatusPolicies(),
+			validStatusCodes(rc.RetryableStatusCodes),
+			validNonNegative(rc.MaxElapsed, "max elapsed"),
+			rc.TLS.Validate(),
+			validProxyURL(rc.ProxyURL),
+			rc.Transport.Validate(),
+			rc.Auth.Validate(),
+			rc.RateLimit.Validate(),
+			validNonNegative(rc.Timeout, "timeout"),
+			rc.Budget.Validate(),
+			validNonNegativeInt(rc.MaxDrainBytes, "max drain bytes"),
+			rc.CircuitBreaker.Validate(),
+		)
 		rc.isValid = err == nil
 	}
 	return
 }
 
-// NewClient should be called only after Validate has been called, to make sure
-// that rc is a valid RetryConfig
+// Clone returns a deep copy of rc, which is valid if rc is valid; the clone can then be
+// modified (and re-validated) without affecting rc
+func (rc *RetryConfig) Clone() *RetryConfig {
+	if rc == nil {
+		return nil
+	}
+	c := *rc
+	c.RetryableStatusCodes = slices.Clone(rc.RetryableStatusCodes)
+	c.StatusPolicies = maps.Clone(rc.StatusPolicies)
+	if rc.TLS != nil {
+		tc := *rc.TLS
+		c.TLS = &tc
+	}
+	if rc.Transport != nil {
+		tc := *rc.Transport
+		c.Transport = &tc
+	}
+	if rc.Budget != nil {
+		bc := *rc.Budget
+		c.Budget = &bc
+	}
+	if rc.Auth != nil {
+		ac := *rc.Auth
+		c.Auth = &ac
+	}
+	if rc.RateLimit != nil {
+		rl := *rc.RateLimit
+		c.RateLimit = &rl
+	}
+	if rc.CircuitBreaker != nil {
+		cb := *rc.CircuitBreaker
+		c.CircuitBreaker = &cb
+	}
+	return &c
+}
+
+// IsValid returns true if Validate has been called on rc and has succeeded; note that
+// modifying the fields of rc afterwards does not reset it, Validate must be called again
+func (rc *RetryConfig) IsValid() bool {
+	return rc != nil && rc.isValid
+}
+
+// NewClient returns a retrying client per rc (a nil rc means the retryablehttp defaults);
+// if rc has not been validated yet (see IsValid), NewClient validates it, returning the
+// validation error if any. As Validate modifies rc, it should preferably be called before
+// rc is shared across goroutines. If rt is nil, the transport of the attempts is built by NewTransport,
+// i.e. it is a clone of http.DefaultTransport (per rc, if set), hence it does not share mutable state
+// (e.g. idle connections) with http.DefaultTransport
 func (rc *RetryConfig) NewClient(rt http.RoundTripper, logger interface{}) (*http.Client, error) {
+	t, err := rc.NewRoundTripper(rt, logger)
+	if err != nil {
+		return nil, err
+	}
+	hc := &http.Client{Transport: t}
+	if rc != nil {
+		hc.Timeout = rc.Timeout
+	}
+	return hc, nil
+}
+
+// NewRoundTripper behaves like NewClient, only that it returns the retrying RoundTripper of the client
+// (wrapping rt), e.g. for libraries which accept an http.RoundTripper rather than an *http.Client; it has
+// the same retry behavior as the client, except for Timeout, which is a client setting. The returned
+// RoundTripper is safe for concurrent use
+func (rc *RetryConfig) NewRoundTripper(rt http.RoundTripper, logger interface{}) (*RetryTransport, error) {
+	c, err := rc.NewRetryableClient(rt, logger)
+	if err != nil {
+		return nil, err
+	}
+	t := &RetryTransport{rt: &hrhttp.RoundTripper{Client: c}}
+	if rc != nil {
+		if rc.Coalesce {
+			t.coalescer = &coalescer{}
+		}
+		if rc.Budget != nil {
+			t.budget = newRetryBudget(*rc.Budget)
+		}
+		if rc.CircuitBreaker != nil {
+			t.breaker = newCircuitBreaker(*rc.CircuitBreaker, rc.RetryableStatusCodes)
+		}
+	}
+	return t, nil
+}
+
+// NewRetryableClient behaves like NewClient, only that it returns the underlying retryablehttp client,
+// so that its hooks (RequestLogHook, ResponseLogHook, ErrorHandler, PrepareRetry) can be set;
+// its ErrorHandler returns a RetriesExhaustedError.
+// Note that MaxElapsed, Observer, Budget and CircuitBreaker rely on the per request state tracked by the client returned
+// by NewClient, hence they do not apply to requests sent directly by the Do method of the retryablehttp client
+func (rc *RetryConfig) NewRetryableClient(rt http.RoundTripper, logger interface{}) (*hrhttp.Client, error) {
 	c := hrhttp.NewClient()
 	if rc != nil {
 		if !rc.isValid {
-			return nil, fmt.Errorf("retry configuration is not valid")
+			if err := rc.Validate(); err != nil {
+				return nil, fmt.Errorf("retry configuration is not valid: %w", err)
+			}
 		}
 		c.RetryWaitMin = rc.WaitMin
 		c.RetryWaitMax = rc.WaitMax
 		c.RetryMax = rc.MaxAttempts
-		c.Backoff = rc.backoff
+		c.Backoff = rc.clientBackoff(rc.newRand())
+		c.CheckRetry = rc.checkRetry()
+		c.RequestLogHook = rc.requestLogHook()
+		c.ResponseLogHook = rc.ResponseLogHook
 	}
+	var err error
+	if rt == nil {
+		// a clone of http.DefaultTransport, so that the client does not share its mutable state
+		if rt, err = rc.NewTransport(); err != nil {
+			return nil, err
+		}
+	}
+	if rc != nil && rc.Auth != nil {
+		var header string
+		if header, err = rc.Auth.header(); err != nil {
+			return nil, err
+		}
+		rt = &authTransport{rt: rt, header: header}
+	}
+	if rc != nil && rc.WrapTransport != nil {
+		rt = rc.WrapTransport(rt)
+	}
+	if rc != nil && rc.RateLimit != nil {
+		rt = &rateLimitTransport{rt: rt, limiter: rc.RateLimit.newLimiter()}
+	}
+	c.ErrorHandler = exhaustedErrorHandler
 	c.HTTPClient = &http.Client{Transport: rt}
 	// set the logger (hrhttp default logger is debug-level, too verbose)
-	if logger != nil {
-		switch logger.(type) {
-		case hrhttp.Logger, hrhttp.LeveledLogger:
-			// OK
-		default:
-			return nil, fmt.Errorf("invalid logger type %T", logger)
-		}
+	if c.Logger, err = adaptLogger(logger); err != nil {
+		return nil, err
 	}
-	c.Logger = logger
-	return c.StandardClient(), nil
+	return c, nil
 }
 
 func validDurations(d1, d2 time.Duration, equalAllowed bool) (err error) {
@@ -98,9 +416,23 @@ func validDurations(d1, d2 time.Duration, equalAllowed bool) (err error) {
 	return
 }
 
-func validPositive(n int) (err error) {
-	if n <= 0 {
-		err = fmt.Errorf("number %d must be positive", n)
+func validWaitFloor(waitMin, floor time.Duration) (err error) {
+	if floor > 0 && waitMin < floor {
+		err = fmt.Errorf("wait min %v must not be below the wait floor %v", waitMin, floor)
+	}
+	return
+}
+
+func validNonNegativeInt(n int, name string) (err error) {
+	if n < 0 {
+		err = fmt.Errorf("%s %d must not be negative", name, n)
+	}
+	return
+}
+
+func validNonNegative(d time.Duration, name string) (err error) {
+	if d < 0 {
+		err = fmt.Errorf("%s duration %v must not be negative", name, d)
 	}
 	return
 }
@@ -0,0 +1,168 @@
+package rhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateWaitMax(t *testing.T) {
+	tests := []struct {
+		name    string
+		waitMax time.Duration
+		valid   bool
+	}{
+		{"wait max below wait min", 500 * time.Millisecond, false},
+		{"wait max equal to wait min", time.Second, true},
+		{"wait max above wait min", 30 * time.Second, true},
+		{"wait max unset", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &RetryConfig{WaitMin: time.Second, WaitMax: tt.waitMax, MaxAttempts: 4}
+			if err := rc.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, valid %v", err, tt.valid)
+			}
+			if rc.IsValid() != tt.valid {
+				t.Errorf("IsValid() = %v, want %v", rc.IsValid(), tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	rc := &RetryConfig{WaitMin: 0, WaitMax: 10 * time.Second, MaxAttempts: -1, Policy: "bogus"}
+	err := rc.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Validate() = %v, want joined errors", err)
+	}
+	if errs := joined.Unwrap(); len(errs) != 3 {
+		t.Errorf("Validate() reported %d problems, want 3: %v", len(errs), err)
+	}
+	for _, want := range []string{"invalid durations: 0s", "max attempts -1", "invalid backoff policy bogus"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %q, missing %q", err, want)
+		}
+	}
+	if rc.IsValid() {
+		t.Error("IsValid() = true after failed validation")
+	}
+}
+
+func TestDefaultRetryConfigIsValid(t *testing.T) {
+	rc := DefaultRetryConfig()
+	if !rc.IsValid() {
+		t.Fatal("IsValid() = false")
+	}
+	if err := rc.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if rc.WaitMin != time.Second || rc.WaitMax != 30*time.Second || rc.MaxAttempts != 4 || rc.Policy != ExponentialPolicy {
+		t.Errorf("DefaultRetryConfig() = %+v, not the recommended values", rc)
+	}
+}
+
+type traceKey struct{}
+
+// recordingTransport records the attempts it sends
+type recordingTransport struct {
+	rt       http.RoundTripper
+	mu       sync.Mutex
+	attempts []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.attempts = append(t.attempts, req)
+	t.mu.Unlock()
+	return t.rt.RoundTrip(req)
+}
+
+func TestWrapTransportSeesEachAttempt(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	rec := &recordingTransport{}
+	rc := &RetryConfig{
+		WaitMin:     time.Millisecond,
+		WaitMax:     time.Millisecond,
+		MaxAttempts: 3,
+		WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
+			rec.rt = rt
+			return rec
+		},
+	}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const traceID, traceParent = "trace-1", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	ctx := context.WithValue(context.Background(), traceKey{}, traceID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("traceparent", traceParent)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if len(rec.attempts) != 3 {
+		t.Fatalf("wrapped attempts = %d, want 3", len(rec.attempts))
+	}
+	for i, attempt := range rec.attempts {
+		if id, _ := attempt.Context().Value(traceKey{}).(string); id != traceID {
+			t.Errorf("attempt %d: context value = %q, want %q", i, id, traceID)
+		}
+		if tp := attempt.Header.Get("traceparent"); tp != traceParent {
+			t.Errorf("attempt %d: traceparent = %q, want %q", i, tp, traceParent)
+		}
+	}
+}
+
+func TestNewClientWithNilRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	for name, rc := range map[string]*RetryConfig{"nil config": nil, "default config": DefaultRetryConfig()} {
+		t.Run(name, func(t *testing.T) {
+			c, err := rc.NewClient(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			rtc, err := rc.NewRetryableClient(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr, ok := rtc.HTTPClient.Transport.(*http.Transport); !ok || tr == http.DefaultTransport {
+				t.Errorf("transport = %v, want a clone of http.DefaultTransport", rtc.HTTPClient.Transport)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package rhttp
+
+import (
+	"bytes"
+	"golang.org/x/sync/singleflight"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// maxCoalescedBodyBytes caps the body of a shared response, which is read into memory;
+// the response of a request in flight with a longer body is not shared
+const maxCoalescedBodyBytes = 1 << 20
+
+// coalescer dedups in-flight identical requests (see RetryConfig.Coalesce)
+type coalescer struct {
+	group singleflight.Group
+}
+
+// bufferedResponse is a response whose body has been read, so that it can be shared
+type bufferedResponse struct {
+	resp *http.Response
+	body []byte
+}
+
+// coalescable returns true for requests which can be shared: GET / HEAD without a body, nor credentials
+// or a range, as their responses are specific to the request
+func coalescable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	for _, h := range []string{"Authorization", "Proxy-Authorization", "Cookie", "Range"} {
+		if _, ok := req.Header[h]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// coalesceKey returns the key of identical requests: the method, the URL and the content negotiation
+// (Accept*) headers, which select the representation of the response
+func coalesceKey(req *http.Request) string {
+	var sb strings.Builder
+	sb.WriteString(req.Method + " " + req.URL.String())
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		if strings.HasPrefix(name, "Accept") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		sb.WriteString("\n" + name + ": " + strings.Join(req.Header[name], ", "))
+	}
+	return sb.String()
+}
+
+// roundTrip sends req using send, unless an identical request (see coalesceKey) is already in flight,
+// in which case it waits for the response of that request, and returns a copy of it. If the body of
+// the response is longer than maxCoalescedBodyBytes, the request in flight gets it as is, while
+// the identical requests are sent on their own
+func (c *coalescer) roundTrip(send func(*http.Request) (*http.Response, error), req *http.Request) (*http.Response, error) {
+	var own *http.Response
+	v, err, _ := c.group.Do(coalesceKey(req), func() (interface{}, error) {
+		resp, err := send(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxCoalescedBodyBytes+1))
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+		if len(body) > maxCoalescedBodyBytes {
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			own = resp
+			return nil, nil
+		}
+		_ = resp.Body.Close()
+		return &bufferedResponse{resp: resp, body: body}, nil
+	})
+	if own != nil {
+		return own, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	br, ok := v.(*bufferedResponse)
+	if !ok {
+		// the response of the request in flight is too long to be shared
+		return send(req)
+	}
+	resp := *br.resp
+	resp.Header = br.resp.Header.Clone()
+	resp.Trailer = br.resp.Trailer.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(br.body))
+	resp.Request = req
+	return &resp, nil
+}
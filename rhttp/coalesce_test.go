@@ -0,0 +1,207 @@
+package rhttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesceKey(t *testing.T) {
+	newRequest := func(method, url string, header ...string) *http.Request {
+		req := httptest.NewRequest(method, url, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Add(header[i], header[i+1])
+		}
+		return req
+	}
+	base := newRequest(http.MethodGet, "http://example.com/a?b=1")
+	tests := []struct {
+		name  string
+		req   *http.Request
+		equal bool
+	}{
+		{"same request", newRequest(http.MethodGet, "http://example.com/a?b=1"), true},
+		{"other headers", newRequest(http.MethodGet, "http://example.com/a?b=1", "Traceparent", "00-1"), true},
+		{"method", newRequest(http.MethodHead, "http://example.com/a?b=1"), false},
+		{"path", newRequest(http.MethodGet, "http://example.com/b?b=1"), false},
+		{"query", newRequest(http.MethodGet, "http://example.com/a?b=2"), false},
+		{"host", newRequest(http.MethodGet, "http://example.org/a?b=1"), false},
+		{"accept", newRequest(http.MethodGet, "http://example.com/a?b=1", "Accept", "application/json"), false},
+		{"accept encoding", newRequest(http.MethodGet, "http://example.com/a?b=1", "Accept-Encoding", "gzip"), false},
+		{"accept language", newRequest(http.MethodGet, "http://example.com/a?b=1", "Accept-Language", "fr"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := coalesceKey(tt.req) == coalesceKey(base); equal != tt.equal {
+				t.Errorf("coalesceKey(%q) == coalesceKey(%q) is %v, want %v", coalesceKey(tt.req), coalesceKey(base), equal, tt.equal)
+			}
+		})
+	}
+	accept := newRequest(http.MethodGet, "http://example.com", "Accept", "text/html", "Accept-Language", "fr")
+	reordered := newRequest(http.MethodGet, "http://example.com", "Accept-Language", "fr", "Accept", "text/html")
+	if coalesceKey(accept) != coalesceKey(reordered) {
+		t.Errorf("coalesceKey() depends on the order of the headers: %q, %q", coalesceKey(accept), coalesceKey(reordered))
+	}
+}
+
+func TestCoalescable(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   io.Reader
+		header string
+		want   bool
+	}{
+		{"get", http.MethodGet, nil, "", true},
+		{"head", http.MethodHead, nil, "", true},
+		{"post", http.MethodPost, nil, "", false},
+		{"get with body", http.MethodGet, strings.NewReader("x"), "", false},
+		{"authorization", http.MethodGet, nil, "Authorization", false},
+		{"proxy authorization", http.MethodGet, nil, "Proxy-Authorization", false},
+		{"cookie", http.MethodGet, nil, "Cookie", false},
+		{"range", http.MethodGet, nil, "Range", false},
+		{"accept", http.MethodGet, nil, "Accept", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "http://example.com", tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set(tt.header, "x")
+			}
+			if got := coalescable(req); got != tt.want {
+				t.Errorf("coalescable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// coalesceServer returns a server which holds each request until n requests have arrived (or a timeout),
+// so that they are in flight together, and responds with body(r); it counts the requests
+func coalesceServer(t *testing.T, n int32, body func(r *http.Request) []byte) (*httptest.Server, *atomic.Int32) {
+	var arrived atomic.Int32
+	all := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if arrived.Add(1) == n {
+			close(all)
+		}
+		select {
+		case <-all:
+		case <-time.After(200 * time.Millisecond):
+		}
+		_, _ = w.Write(body(r))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &arrived
+}
+
+// sendConcurrently sends the n requests returned by newRequest concurrently, returning the bodies of the responses
+func sendConcurrently(t *testing.T, c *http.Client, n int, newRequest func(i int) *http.Request) [][]byte {
+	bodies := make([][]byte, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := c.Do(newRequest(i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			if bodies[i], err = io.ReadAll(resp.Body); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return bodies
+}
+
+func newCoalescingClient(t *testing.T) *http.Client {
+	rc := &RetryConfig{WaitMin: time.Millisecond, WaitMax: time.Millisecond, MaxAttempts: 1, Coalesce: true}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCoalesceSharesIdenticalRequests(t *testing.T) {
+	const n = 5
+	payload := []byte("shared response")
+	srv, arrived := coalesceServer(t, n, func(*http.Request) []byte { return payload })
+	c := newCoalescingClient(t)
+	bodies := sendConcurrently(t, c, n, func(int) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		return req
+	})
+	if got := arrived.Load(); got != 1 {
+		t.Errorf("requests sent = %d, want 1", got)
+	}
+	// each request gets its own copy of the body
+	for i, body := range bodies {
+		if !bytes.Equal(body, payload) {
+			t.Errorf("body %d = %q, want %q", i, body, payload)
+		}
+	}
+}
+
+func TestCoalesceIsolatesHeaders(t *testing.T) {
+	headers := []string{"Authorization", "Cookie", "Range", "Accept"}
+	for _, header := range headers {
+		t.Run(header, func(t *testing.T) {
+			const n = 4
+			srv, arrived := coalesceServer(t, n, func(r *http.Request) []byte { return []byte(r.Header.Get(header)) })
+			c := newCoalescingClient(t)
+			bodies := sendConcurrently(t, c, n, func(i int) *http.Request {
+				req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+				req.Header.Set(header, string(rune('a'+i)))
+				return req
+			})
+			if got := arrived.Load(); got != n {
+				t.Errorf("requests sent = %d, want %d", got, n)
+			}
+			for i, body := range bodies {
+				if want := string(rune('a' + i)); string(body) != want {
+					t.Errorf("request %d got the response of %q", i, body)
+				}
+			}
+		})
+	}
+}
+
+func TestCoalesceDoesNotShareLongBodies(t *testing.T) {
+	const n = 3
+	payload := bytes.Repeat([]byte("x"), maxCoalescedBodyBytes+1)
+	var sent atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// the first request is held, so that the others are coalesced with it
+		if sent.Add(1) == 1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		_, _ = w.Write(payload)
+	}))
+	defer srv.Close()
+	c := newCoalescingClient(t)
+	bodies := sendConcurrently(t, c, n, func(int) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		return req
+	})
+	if got := sent.Load(); got != n {
+		t.Errorf("requests sent = %d, want %d", got, n)
+	}
+	for i, body := range bodies {
+		if !bytes.Equal(body, payload) {
+			t.Errorf("body %d has %d bytes, want %d", i, len(body), len(payload))
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package rhttp
+
+import (
+	"context"
+)
+
+type maxAttemptsKey struct{}
+
+// ContextWithMaxAttempts returns a copy of ctx which overrides the MaxAttempts of the client for the
+// requests sent with it, e.g. 0 for health checks which should not be retried; a negative n means 0.
+// The override can only reduce the retries: an n greater than the MaxAttempts of the client has no
+// effect. Once the retries allowed by the override have failed, the client returns a RetriesExhaustedError,
+// as it does for MaxAttempts. Like MaxElapsed, it relies on the per request state tracked by the client
+// returned by NewClient, hence for requests sent directly by the Do method of the retryablehttp client
+// only 0 applies
+func ContextWithMaxAttempts(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxAttemptsKey{}, max(n, 0))
+}
+
+// maxAttemptsOf returns the MaxAttempts override of ctx, ok is false if there is none
+func maxAttemptsOf(ctx context.Context) (n int, ok bool) {
+	n, ok = ctx.Value(maxAttemptsKey{}).(int)
+	return
+}
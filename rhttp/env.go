@@ -0,0 +1,124 @@
+package rhttp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/densify-dev/net-utils/common"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	yamlTag    = "yaml"
+	yamlIgnore = "-"
+	underscore = "_"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadFromEnv overlays the values of environment variables onto rc, e.g. when rc has been unmarshalled
+// from a yaml file; only the fields whose environment variables are set are overridden. The name of the
+// environment variable of a field is its yaml key in upper case, prefixed by prefix and '_' (if prefix is not
+// empty), e.g. RETRY_MAX_ATTEMPTS for the prefix RETRY; the keys of nested sections are prefixed by the
+// key of the section, e.g. RETRY_TLS_CA_FILE. Durations are parsed per time.ParseDuration, and lists as
+// comma-separated values. All the parsing errors are reported, joined (see errors.Join).
+// As it modifies rc, LoadFromEnv resets its validation, hence rc must be validated afterwards
+// (NewClient does it)
+func (rc *RetryConfig) LoadFromEnv(prefix string) error {
+	if rc == nil {
+		return fmt.Errorf("nil retry configuration")
+	}
+	rc.isValid = false
+	_, err := loadFromEnv(reflect.ValueOf(rc).Elem(), prefix)
+	return err
+}
+
+// loadFromEnv overlays the environment variables onto the fields of the struct v, returning true
+// if any of them has been set
+func loadFromEnv(v reflect.Value, prefix string) (set bool, err error) {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get(yamlTag), common.Comma)
+		if !f.IsExported() || key == common.Empty || key == yamlIgnore {
+			continue
+		}
+		name := envName(prefix, key)
+		fv := v.Field(i)
+		if f.Type.Kind() == reflect.Pointer && f.Type.Elem().Kind() == reflect.Struct {
+			// a nested section, allocated only if any of its environment variables is set
+			nested := fv
+			if nested.IsNil() {
+				nested = reflect.New(f.Type.Elem())
+			}
+			nestedSet, nestedErr := loadFromEnv(nested.Elem(), name)
+			if nestedSet && fv.IsNil() {
+				fv.Set(nested)
+			}
+			set = set || nestedSet
+			errs = append(errs, nestedErr)
+			continue
+		}
+		if s, ok := os.LookupEnv(name); ok {
+			set = true
+			if setErr := setFromEnv(fv, s); setErr != nil {
+				errs = append(errs, fmt.Errorf("invalid environment variable %s: %w", name, setErr))
+			}
+		}
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+func envName(prefix, key string) string {
+	name := strings.ToUpper(key)
+	if prefix != common.Empty {
+		name = prefix + underscore + name
+	}
+	return name
+}
+
+func setFromEnv(v reflect.Value, s string) (err error) {
+	if v.Type() == durationType {
+		var d time.Duration
+		if d, err = time.ParseDuration(s); err == nil {
+			v.SetInt(int64(d))
+		}
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, v.Type().Bits()); err == nil {
+			v.SetInt(n)
+		}
+	case reflect.Float64:
+		var x float64
+		if x, err = strconv.ParseFloat(s, 64); err == nil {
+			v.SetFloat(x)
+		}
+	case reflect.Slice:
+		elems := strings.Split(s, common.Comma)
+		slice := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err = setFromEnv(slice.Index(i), strings.TrimSpace(elem)); err != nil {
+				return
+			}
+		}
+		v.Set(slice)
+	default:
+		err = fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return
+}
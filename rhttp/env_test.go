@@ -0,0 +1,112 @@
+package rhttp
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFromEnv(t *testing.T) {
+	t.Setenv("RETRY_WAIT_MIN", "250ms")
+	t.Setenv("RETRY_MAX_ATTEMPTS", "7")
+	t.Setenv("RETRY_POLICY", ConstantPolicy)
+	t.Setenv("RETRY_RESPECT_RETRY_AFTER", "true")
+	t.Setenv("RETRY_RETRYABLE_STATUS_CODES", "502, 503,504")
+	t.Setenv("RETRY_BUDGET_RATIO", "0.25")
+	t.Setenv("RETRY_BUDGET_MAX_TOKENS", "10")
+	rc := DefaultRetryConfig()
+	if err := rc.LoadFromEnv("RETRY"); err != nil {
+		t.Fatalf("LoadFromEnv() = %v", err)
+	}
+	if rc.IsValid() {
+		t.Error("IsValid() = true after LoadFromEnv, want the validation to be reset")
+	}
+	if rc.WaitMin != 250*time.Millisecond {
+		t.Errorf("WaitMin = %v, want 250ms", rc.WaitMin)
+	}
+	if rc.WaitMax != defaultWaitMax {
+		t.Errorf("WaitMax = %v, want %v (no environment variable)", rc.WaitMax, defaultWaitMax)
+	}
+	if rc.MaxAttempts != 7 {
+		t.Errorf("MaxAttempts = %d, want 7", rc.MaxAttempts)
+	}
+	if rc.Policy != ConstantPolicy {
+		t.Errorf("Policy = %s, want %s", rc.Policy, ConstantPolicy)
+	}
+	if !rc.RespectRetryAfter {
+		t.Error("RespectRetryAfter = false, want true")
+	}
+	if want := []int{502, 503, 504}; !slices.Equal(rc.RetryableStatusCodes, want) {
+		t.Errorf("RetryableStatusCodes = %v, want %v", rc.RetryableStatusCodes, want)
+	}
+	// a nested section is allocated when any of its environment variables is set, and only then
+	if rc.Budget == nil || rc.Budget.Ratio != 0.25 || rc.Budget.MaxTokens != 10 {
+		t.Errorf("Budget = %+v, want a ratio of 0.25 and 10 max tokens", rc.Budget)
+	}
+	if rc.TLS != nil {
+		t.Errorf("TLS = %+v, want nil (no environment variable)", rc.TLS)
+	}
+	if err := rc.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestLoadFromEnvReportsAllErrors(t *testing.T) {
+	t.Setenv("WAIT_MIN", "soon")
+	t.Setenv("MAX_ATTEMPTS", "many")
+	t.Setenv("RESPECT_RETRY_AFTER", "perhaps")
+	t.Setenv("RETRYABLE_STATUS_CODES", "502,bad")
+	err := DefaultRetryConfig().LoadFromEnv("")
+	if err == nil {
+		t.Fatal("LoadFromEnv() = nil, want an error")
+	}
+	for _, name := range []string{"WAIT_MIN", "MAX_ATTEMPTS", "RESPECT_RETRY_AFTER", "RETRYABLE_STATUS_CODES"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("LoadFromEnv() = %v, want an error for %s", err, name)
+		}
+	}
+	var rc *RetryConfig
+	if err = rc.LoadFromEnv(""); err == nil {
+		t.Error("LoadFromEnv() of a nil configuration = nil, want an error")
+	}
+}
+
+func TestSetFromEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		ptr   interface{}
+		s     string
+		want  interface{}
+		valid bool
+	}{
+		{"string", new(string), "const", "const", true},
+		{"bool", new(bool), "true", true, true},
+		{"invalid bool", new(bool), "yes please", false, false},
+		{"int", new(int), "-3", -3, true},
+		{"invalid int", new(int), "3.5", 0, false},
+		{"int64", new(int64), "1099511627776", int64(1 << 40), true},
+		{"float64", new(float64), "0.5", 0.5, true},
+		{"duration", new(time.Duration), "1m30s", 90 * time.Second, true},
+		{"invalid duration", new(time.Duration), "90", time.Duration(0), false},
+		{"slice", new([]int), "1, 2,3", []int{1, 2, 3}, true},
+		{"string slice", new([]string), "a,b", []string{"a", "b"}, true},
+		{"invalid slice", new([]int), "1,x", []int(nil), false},
+		{"unsupported uint", new(uint), "1", uint(0), false},
+		{"unsupported map", new(map[string]string), "a=b", map[string]string(nil), false},
+		{"unsupported struct", new(struct{ A int }), "1", struct{ A int }{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := reflect.ValueOf(tt.ptr).Elem()
+			err := setFromEnv(v, tt.s)
+			if (err == nil) != tt.valid {
+				t.Fatalf("setFromEnv(%q) = %v, valid %v", tt.s, err, tt.valid)
+			}
+			if got := v.Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("setFromEnv(%q) set %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
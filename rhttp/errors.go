@@ -0,0 +1,73 @@
+package rhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// the maximum number of bytes read from the body of the last response before closing it, as retryablehttp does
+const respReadLimit = 4096
+
+// RetriesExhaustedError is returned by the clients constructed by NewClient / NewRetryableClient, when
+// a request has failed on its last attempt, i.e. all its attempts have failed or retries have been
+// given up (see MaxElapsed, Budget and ContextWithMaxAttempts); errors.As recovers it from the error
+// returned by the client. If the request context is done, e.g. cancelled during the wait before a
+// retry, the client returns the context error instead, promptly
+type RetriesExhaustedError struct {
+	// Attempts is the number of attempts made
+	Attempts int
+	// Response is the response of the last attempt, nil if it failed without a response;
+	// its body has been drained and closed
+	Response *http.Response
+	// Err is the error of the last attempt, nil if it failed with a (retryable) response
+	Err error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	msg := fmt.Sprintf("giving up after %d attempt(s)", e.Attempts)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	} else if e.Response != nil {
+		msg += fmt.Sprintf(": last response status %s", e.Response.Status)
+	}
+	return msg
+}
+
+func (e *RetriesExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// exhaustedErrorHandler is the ErrorHandler of the clients constructed by NewRetryableClient; if the request
+// context is done (the retry policy stops retrying then), the context error is returned as is, as the request
+// has been given up by the caller rather than by the retry policy
+func exhaustedErrorHandler(resp *http.Response, err error, numTries int) (*http.Response, error) {
+	if resp != nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, respReadLimit))
+		_ = resp.Body.Close()
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var givenUp *retriesGivenUpError
+	if errors.As(err, &givenUp) {
+		err = givenUp.err
+	}
+	return nil, &RetriesExhaustedError{Attempts: numTries, Response: resp, Err: err}
+}
+
+// retriesGivenUpError is returned to retryablehttp by the retry policies which give up retrying (see giveUp);
+// err is the error of the last attempt, nil if it has a response
+type retriesGivenUpError struct {
+	err error
+}
+
+func (e *retriesGivenUpError) Error() string {
+	return "retries given up"
+}
+
+func (e *retriesGivenUpError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,124 @@
+package rhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newUnavailableServer returns a server which always responds 503, counting the requests
+func newUnavailableServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestGivenUpRetriesReturnRetriesExhaustedError(t *testing.T) {
+	tests := []struct {
+		name         string
+		rc           *RetryConfig
+		ctx          context.Context
+		wantAttempts int
+	}{
+		{
+			name:         "max attempts",
+			rc:           &RetryConfig{WaitMin: time.Millisecond, WaitMax: time.Millisecond, MaxAttempts: 2},
+			ctx:          context.Background(),
+			wantAttempts: 3,
+		},
+		{
+			name:         "context max attempts",
+			rc:           &RetryConfig{WaitMin: time.Millisecond, WaitMax: time.Millisecond, MaxAttempts: 3},
+			ctx:          ContextWithMaxAttempts(context.Background(), 1),
+			wantAttempts: 2,
+		},
+		{
+			name:         "context without retries",
+			rc:           &RetryConfig{WaitMin: time.Millisecond, WaitMax: time.Millisecond, MaxAttempts: 3},
+			ctx:          ContextWithMaxAttempts(context.Background(), 0),
+			wantAttempts: 1,
+		},
+		{
+			name:         "max elapsed",
+			rc:           &RetryConfig{WaitMin: 50 * time.Millisecond, WaitMax: 50 * time.Millisecond, MaxAttempts: 10, Policy: ConstantPolicy, MaxElapsed: 25 * time.Millisecond},
+			ctx:          context.Background(),
+			wantAttempts: 2,
+		},
+		{
+			name:         "budget",
+			rc:           &RetryConfig{WaitMin: time.Millisecond, WaitMax: time.Millisecond, MaxAttempts: 3, Budget: &RetryBudget{MaxTokens: 1}},
+			ctx:          context.Background(),
+			wantAttempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := newUnavailableServer(t)
+			c, err := tt.rc.NewClient(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequestWithContext(tt.ctx, http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Do(req)
+			if resp != nil {
+				_ = resp.Body.Close()
+				t.Fatalf("Do() = %d response, want an error", resp.StatusCode)
+			}
+			var exhausted *RetriesExhaustedError
+			if !errors.As(err, &exhausted) {
+				t.Fatalf("Do() = %v, want a RetriesExhaustedError", err)
+			}
+			if exhausted.Attempts != tt.wantAttempts || int(calls.Load()) != tt.wantAttempts {
+				t.Errorf("attempts = %d (%d calls), want %d", exhausted.Attempts, calls.Load(), tt.wantAttempts)
+			}
+			if exhausted.Response == nil || exhausted.Response.StatusCode != http.StatusServiceUnavailable || exhausted.Err != nil {
+				t.Errorf("RetriesExhaustedError = %v, want the last 503 response", exhausted)
+			}
+		})
+	}
+}
+
+func TestContextDoneDuringBackoffReturnsPromptly(t *testing.T) {
+	srv, calls := newUnavailableServer(t)
+	rc := &RetryConfig{WaitMin: 5 * time.Second, WaitMax: 5 * time.Second, MaxAttempts: 3, Policy: ConstantPolicy}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	resp, err := c.Do(req)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Do() returned after %v, want promptly after the context deadline", elapsed)
+	}
+	if resp != nil {
+		_ = resp.Body.Close()
+		t.Fatalf("Do() = %d response, want an error", resp.StatusCode)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	var exhausted *RetriesExhaustedError
+	if errors.As(err, &exhausted) {
+		t.Errorf("Do() = %v, want the context error rather than a RetriesExhaustedError", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+}
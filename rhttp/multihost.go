@@ -0,0 +1,87 @@
+package rhttp
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// MultiHostConfig selects a RetryConfig per host; the keys of Hosts are hosts or host:port pairs,
+// which may also be patterns (see path.Match), e.g. "*.example.com"; Default applies to hosts
+// which are not matched by any key
+type MultiHostConfig struct {
+	Default *RetryConfig            `yaml:"default,omitempty"`
+	Hosts   map[string]*RetryConfig `yaml:"hosts,omitempty"`
+}
+
+// Validate must be called once, after mhc has been constructed / unmarshalled; it validates
+// the default and every host entry, reporting all the entries which failed
+func (mhc *MultiHostConfig) Validate() (err error) {
+	if mhc != nil {
+		var errs []error
+		if dErr := mhc.Default.Validate(); dErr != nil {
+			errs = append(errs, fmt.Errorf("default retry configuration: %w", dErr))
+		}
+		for _, key := range mhc.keys() {
+			if _, pErr := path.Match(key, key); pErr != nil {
+				errs = append(errs, fmt.Errorf("host %s: invalid pattern: %w", key, pErr))
+			} else if hErr := mhc.Hosts[key].Validate(); hErr != nil {
+				errs = append(errs, fmt.Errorf("host %s retry configuration: %w", key, hErr))
+			}
+		}
+		err = errors.Join(errs...)
+	}
+	return
+}
+
+// ConfigForHost returns the RetryConfig for host (which may include a port); hosts are matched
+// case-insensitively, in the following order of precedence:
+//  1. A key equal to host
+//  2. A key equal to host without its port
+//  3. The first key (in lexical order) matching host as a pattern
+//  4. The first key (in lexical order) matching host without its port as a pattern
+//
+// If no key matches, Default is returned
+func (mhc *MultiHostConfig) ConfigForHost(host string) *RetryConfig {
+	if mhc == nil {
+		return nil
+	}
+	candidates := []string{strings.ToLower(host)}
+	if h, _, err := net.SplitHostPort(candidates[0]); err == nil {
+		candidates = append(candidates, h)
+	}
+	keys := mhc.keys()
+	for _, match := range []func(key, candidate string) bool{equalKey, matchKey} {
+		for _, candidate := range candidates {
+			for _, key := range keys {
+				if match(key, candidate) {
+					return mhc.Hosts[key]
+				}
+			}
+		}
+	}
+	return mhc.Default
+}
+
+// NewClientForHost returns a client per the RetryConfig selected for host (see ConfigForHost)
+func (mhc *MultiHostConfig) NewClientForHost(host string, rt http.RoundTripper, logger interface{}) (*http.Client, error) {
+	return mhc.ConfigForHost(host).NewClient(rt, logger)
+}
+
+func (mhc *MultiHostConfig) keys() []string {
+	return slices.Sorted(maps.Keys(mhc.Hosts))
+}
+
+func equalKey(key, candidate string) bool {
+	return strings.ToLower(key) == candidate
+}
+
+func matchKey(key, candidate string) bool {
+	matched, _ := path.Match(strings.ToLower(key), candidate)
+	return matched
+}
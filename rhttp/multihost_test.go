@@ -0,0 +1,73 @@
+package rhttp
+
+import (
+	"testing"
+)
+
+func TestConfigForHost(t *testing.T) {
+	configs := map[string]*RetryConfig{}
+	for _, key := range []string{"default", "api.example.com", "api.example.com:8443", "*.example.com", "*.example.com:8443", "a*.example.com", "*:9000"} {
+		configs[key] = &RetryConfig{Policy: key}
+	}
+	mhc := &MultiHostConfig{Default: configs["default"], Hosts: map[string]*RetryConfig{}}
+	for key, rc := range configs {
+		if key != "default" {
+			mhc.Hosts[key] = rc
+		}
+	}
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"api.example.com", "api.example.com"},
+		{"API.Example.COM", "api.example.com"},
+		{"api.example.com:8443", "api.example.com:8443"},
+		// an exact key without the port takes precedence over the patterns with the port
+		{"api.example.com:443", "api.example.com"},
+		// the first pattern in lexical order: "*.example.com" < "a*.example.com"
+		{"app.example.com", "*.example.com"},
+		{"web.example.com", "*.example.com"},
+		// a pattern with the port takes precedence over a pattern without it
+		{"web.example.com:8443", "*.example.com:8443"},
+		{"web.example.com:80", "*.example.com"},
+		{"localhost:9000", "*:9000"},
+		// a pattern does not match across the dots
+		{"example.com", "default"},
+		{"a.b.example.org", "default"},
+		{"localhost", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := mhc.ConfigForHost(tt.host); got != configs[tt.want] {
+				t.Errorf("ConfigForHost(%q) = %+v, want the configuration of %q", tt.host, got, tt.want)
+			}
+		})
+	}
+	var nilConfig *MultiHostConfig
+	if rc := nilConfig.ConfigForHost("api.example.com"); rc != nil {
+		t.Errorf("ConfigForHost() of a nil configuration = %+v, want nil", rc)
+	}
+}
+
+func TestMultiHostConfigValidate(t *testing.T) {
+	valid := DefaultRetryConfig
+	tests := []struct {
+		name  string
+		mhc   *MultiHostConfig
+		valid bool
+	}{
+		{"nil", nil, true},
+		{"default only", &MultiHostConfig{Default: valid()}, true},
+		{"hosts", &MultiHostConfig{Default: valid(), Hosts: map[string]*RetryConfig{"*.example.com": valid()}}, true},
+		{"invalid default", &MultiHostConfig{Default: &RetryConfig{WaitMin: -1}}, false},
+		{"invalid host", &MultiHostConfig{Hosts: map[string]*RetryConfig{"example.com": {Policy: "unknown"}}}, false},
+		{"invalid pattern", &MultiHostConfig{Hosts: map[string]*RetryConfig{"[example.com": valid()}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mhc.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, valid %v", err, tt.valid)
+			}
+		})
+	}
+}
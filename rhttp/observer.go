@@ -0,0 +1,44 @@
+package rhttp
+
+import (
+	"context"
+	hrhttp "github.com/hashicorp/go-retryablehttp"
+	"net/http"
+	"time"
+)
+
+// AttemptObserver observes every attempt of the requests of a client, e.g. to count attempts,
+// retries and outcomes in a metrics backend. ObserveAttempt is called synchronously after each
+// attempt, hence implementations should be cheap; attempt is zero-based, statusCode is zero if
+// the attempt failed without a response, and elapsed is the duration of the attempt
+type AttemptObserver interface {
+	ObserveAttempt(method, host string, attempt, statusCode int, err error, elapsed time.Duration)
+}
+
+// requestLogHook returns the RequestLogHook of a client constructed from rc: the attempt is recorded
+// in the requestState (for the Observer and the retry policies) before rc.RequestLogHook is called
+func (rc *RetryConfig) requestLogHook() hrhttp.RequestLogHook {
+	hook := rc.RequestLogHook
+	return func(l hrhttp.Logger, req *http.Request, attempt int) {
+		if rs := stateOf(req.Context()); rs != nil {
+			rs.method, rs.host, rs.attempt, rs.attemptStart = req.Method, req.URL.Host, attempt, time.Now()
+		}
+		if hook != nil {
+			hook(l, req, attempt)
+		}
+	}
+}
+
+// observingRetryPolicy reports each attempt recorded in the requestState to the observer
+func observingRetryPolicy(check hrhttp.CheckRetry, observer AttemptObserver) hrhttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if rs := stateOf(ctx); rs != nil {
+			var statusCode int
+			if resp != nil {
+				statusCode = resp.StatusCode
+			}
+			observer.ObserveAttempt(rs.method, rs.host, rs.attempt, statusCode, err, time.Since(rs.attemptStart))
+		}
+		return check(ctx, resp, err)
+	}
+}
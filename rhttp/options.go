@@ -0,0 +1,49 @@
+package rhttp
+
+import (
+	"time"
+)
+
+// Option sets a field of the RetryConfig constructed by NewRetryConfig
+type Option func(*RetryConfig)
+
+// NewRetryConfig returns a validated RetryConfig with the recommended values (see DefaultRetryConfig),
+// modified by opts, or the validation error if any
+func NewRetryConfig(opts ...Option) (*RetryConfig, error) {
+	rc := DefaultRetryConfig()
+	for _, opt := range opts {
+		opt(rc)
+	}
+	if err := rc.Validate(); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// WithWaitMin sets the WaitMin of the RetryConfig
+func WithWaitMin(d time.Duration) Option {
+	return func(rc *RetryConfig) {
+		rc.WaitMin = d
+	}
+}
+
+// WithWaitMax sets the WaitMax of the RetryConfig
+func WithWaitMax(d time.Duration) Option {
+	return func(rc *RetryConfig) {
+		rc.WaitMax = d
+	}
+}
+
+// WithMaxAttempts sets the MaxAttempts of the RetryConfig
+func WithMaxAttempts(n int) Option {
+	return func(rc *RetryConfig) {
+		rc.MaxAttempts = n
+	}
+}
+
+// WithPolicy sets the Policy of the RetryConfig
+func WithPolicy(policy string) Option {
+	return func(rc *RetryConfig) {
+		rc.Policy = policy
+	}
+}
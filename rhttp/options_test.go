@@ -0,0 +1,54 @@
+package rhttp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRetryConfig(t *testing.T) {
+	rc, err := NewRetryConfig()
+	if err != nil {
+		t.Fatalf("NewRetryConfig() = %v", err)
+	}
+	if d := DefaultRetryConfig(); rc.WaitMin != d.WaitMin || rc.WaitMax != d.WaitMax || rc.MaxAttempts != d.MaxAttempts || rc.Policy != d.Policy {
+		t.Errorf("NewRetryConfig() = %+v, want the defaults %+v", rc, d)
+	}
+	rc, err = NewRetryConfig(
+		WithWaitMin(100*time.Millisecond),
+		WithWaitMax(2*time.Second),
+		WithMaxAttempts(2),
+		WithPolicy(ConstantJitterPolicy),
+	)
+	if err != nil {
+		t.Fatalf("NewRetryConfig() = %v", err)
+	}
+	if rc.WaitMin != 100*time.Millisecond || rc.WaitMax != 2*time.Second || rc.MaxAttempts != 2 || rc.Policy != ConstantJitterPolicy {
+		t.Errorf("NewRetryConfig() = %+v, not per the options", rc)
+	}
+	if !rc.IsValid() {
+		t.Error("IsValid() = false")
+	}
+	// the options are applied in order
+	if rc, err = NewRetryConfig(WithMaxAttempts(1), WithMaxAttempts(6)); err != nil || rc.MaxAttempts != 6 {
+		t.Errorf("NewRetryConfig() = %+v, %v, want the last MaxAttempts", rc, err)
+	}
+}
+
+func TestNewRetryConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"negative wait min", []Option{WithWaitMin(-time.Second)}},
+		{"wait max below wait min", []Option{WithWaitMin(time.Minute), WithWaitMax(time.Second)}},
+		{"negative max attempts", []Option{WithMaxAttempts(-1)}},
+		{"unknown policy", []Option{WithPolicy("unknown")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rc, err := NewRetryConfig(tt.opts...); err == nil {
+				t.Errorf("NewRetryConfig() = %+v, want an error", rc)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package rhttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+)
+
+// ParseRetryConfig unmarshals the yaml data (see examples/retry.yaml) onto the recommended values
+// (see DefaultRetryConfig), so that omitted keys keep them, and validates the result, e.g. an omitted
+// (or null) max_attempts means 4 retries, while max_attempts: 0 means none. Unknown keys, e.g. typos,
+// are rejected
+func ParseRetryConfig(data []byte) (*RetryConfig, error) {
+	rc := DefaultRetryConfig()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(rc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("cannot unmarshal retry configuration: %w", err)
+	}
+	if err := rc.Validate(); err != nil {
+		return nil, fmt.Errorf("retry configuration is not valid: %w", err)
+	}
+	return rc, nil
+}
@@ -0,0 +1,118 @@
+package rhttp
+
+import (
+	"bytes"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleRetryConfig = `
+wait_min: 500ms
+wait_max: 10s
+max_attempts: 3
+policy: const_jitter
+status_policies:
+  "429": exponential
+  5xx: const
+respect_retry_after: true
+retryable_status_codes: [502, 503, 504]
+max_elapsed: 1m
+transport:
+  max_idle_conns: 50
+  idle_conn_timeout: 30s
+budget:
+  ratio: 0.1
+  refill_rate: 1
+  max_tokens: 10
+circuit_breaker:
+  failure_threshold: 5
+  open_duration: 30s
+`
+
+func TestParseRetryConfigRoundTrip(t *testing.T) {
+	rc, err := ParseRetryConfig([]byte(sampleRetryConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rc.IsValid() {
+		t.Error("IsValid() = false")
+	}
+	if rc.WaitMin != 500*time.Millisecond || rc.WaitMax != 10*time.Second || rc.MaxAttempts != 3 ||
+		rc.Policy != ConstantJitterPolicy || rc.MaxElapsed != time.Minute || !rc.RespectRetryAfter {
+		t.Errorf("ParseRetryConfig() = %+v, not the sample values", rc)
+	}
+	if rc.Budget == nil || rc.Budget.MaxTokens != 10 || rc.CircuitBreaker == nil || rc.CircuitBreaker.FailureThreshold != 5 {
+		t.Errorf("ParseRetryConfig() = %+v, not the sample sections", rc)
+	}
+	data, err := yaml.Marshal(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseRetryConfig(data)
+	if err != nil {
+		t.Fatalf("ParseRetryConfig(%s) = %v", data, err)
+	}
+	againData, err := yaml.Marshal(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, againData) {
+		t.Errorf("round trip mismatch:\n%s\nvs\n%s", data, againData)
+	}
+}
+
+func TestParseRetryConfigDefaults(t *testing.T) {
+	rc, err := ParseRetryConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := DefaultRetryConfig()
+	if rc.WaitMin != def.WaitMin || rc.WaitMax != def.WaitMax || rc.MaxAttempts != def.MaxAttempts || rc.Policy != def.Policy {
+		t.Errorf("ParseRetryConfig(nil) = %+v, want the defaults", rc)
+	}
+}
+
+func TestParseRetryConfigRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"unknown key", "wait_min: 1s\nwait_mn: 2s\n", "field wait_mn not found"},
+		{"unknown nested key", "budget:\n  max_tokens: 10\n  ratoi: 0.1\n", "field ratoi not found"},
+		{"invalid config", "wait_min: 10s\nwait_max: 1s\n", "not valid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRetryConfig([]byte(tt.data)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseRetryConfig() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRetryConfigMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{"omitted", "wait_min: 2s\n", DefaultRetryConfig().MaxAttempts},
+		{"null", "max_attempts:\n", DefaultRetryConfig().MaxAttempts},
+		{"no retries", "max_attempts: 0\n", 0},
+		{"retries", "max_attempts: 2\n", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := ParseRetryConfig([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rc.MaxAttempts != tt.want {
+				t.Errorf("MaxAttempts = %d, want %d", rc.MaxAttempts, tt.want)
+			}
+		})
+	}
+}
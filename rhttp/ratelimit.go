@@ -0,0 +1,45 @@
+package rhttp
+
+import (
+	"fmt"
+	"golang.org/x/time/rate"
+	"net/http"
+)
+
+// RateLimitConfig limits the rate of the attempts of the requests of a client, including retries,
+// which count against the limit; the limit applies per client
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained rate of the attempts, which must be positive
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	// Burst is the maximum number of attempts made at once, above the sustained rate; zero means 1
+	Burst int `yaml:"burst,omitempty"`
+}
+
+// Validate checks that the rate of rl is positive and its burst is not negative
+func (rl *RateLimitConfig) Validate() (err error) {
+	if rl != nil {
+		if rl.RequestsPerSecond <= 0 {
+			err = fmt.Errorf("rate limit %v requests per second must be positive", rl.RequestsPerSecond)
+		} else {
+			err = validNonNegativeInt(rl.Burst, "rate limit burst")
+		}
+	}
+	return
+}
+
+func (rl *RateLimitConfig) newLimiter() *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(rl.RequestsPerSecond), max(rl.Burst, 1))
+}
+
+// rateLimitTransport waits for the limiter before each attempt, respecting the request context
+type rateLimitTransport struct {
+	rt      http.RoundTripper
+	limiter *rate.Limiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.rt.RoundTrip(req)
+}
@@ -0,0 +1,102 @@
+package rhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rl    *RateLimitConfig
+		valid bool
+	}{
+		{"nil", nil, true},
+		{"rate", &RateLimitConfig{RequestsPerSecond: 0.5}, true},
+		{"rate and burst", &RateLimitConfig{RequestsPerSecond: 10, Burst: 5}, true},
+		{"zero rate", &RateLimitConfig{}, false},
+		{"negative rate", &RateLimitConfig{RequestsPerSecond: -1}, false},
+		{"negative burst", &RateLimitConfig{RequestsPerSecond: 1, Burst: -1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rl.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestRateLimitCountsRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// the first attempt fails, the retry succeeds
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	rc := &RetryConfig{
+		WaitMin:     time.Millisecond,
+		WaitMax:     time.Millisecond,
+		MaxAttempts: 2,
+		RateLimit:   &RateLimitConfig{RequestsPerSecond: 10},
+	}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	// 3 attempts, of which the burst of 1 is immediate, and the others wait 100ms each
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		_ = resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("3 attempts took %v, want about 200ms at 10 requests per second", elapsed)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("calls = %d, want 3", n)
+	}
+}
+
+func TestRateLimitRespectsContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	rc := &RetryConfig{
+		WaitMin:   time.Millisecond,
+		WaitMax:   time.Millisecond,
+		RateLimit: &RateLimitConfig{RequestsPerSecond: 0.1},
+	}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	// the next attempt would wait 10s for the limiter, beyond the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if resp, err = c.Do(req); err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("Do() succeeded, want the limiter to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Do() returned after %v, want promptly (the limiter cannot be waited for before the deadline)", elapsed)
+	}
+}
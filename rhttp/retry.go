@@ -0,0 +1,173 @@
+package rhttp
+
+import (
+	"context"
+	"fmt"
+	"github.com/densify-dev/net-utils/common"
+	hrhttp "github.com/hashicorp/go-retryablehttp"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	minStatusCode        = 100
+	maxStatusCode        = 599
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// checkRetry returns the CheckRetry policy of a client constructed from rc
+func (rc *RetryConfig) checkRetry() hrhttp.CheckRetry {
+	check := hrhttp.CheckRetry(hrhttp.DefaultRetryPolicy)
+	if len(rc.RetryableStatusCodes) > 0 {
+		check = statusCodesRetryPolicy(rc.RetryableStatusCodes)
+	}
+	if rc.IdempotentOnly {
+		check = idempotentRetryPolicy(check)
+	}
+	if rc.MaxElapsed > 0 {
+		check = maxElapsedRetryPolicy(check, rc.MaxElapsed)
+	}
+	check = contextMaxAttemptsRetryPolicy(check)
+	if rc.Budget != nil {
+		check = budgetRetryPolicy(check, rc.MaxAttempts)
+	}
+	if rc.Observer != nil {
+		check = observingRetryPolicy(check, rc.Observer)
+	}
+	if rc.MaxDrainBytes > 0 {
+		check = drainLimitRetryPolicy(check, int64(rc.MaxDrainBytes))
+	}
+	return check
+}
+
+// statusCodesRetryPolicy retries transport errors (per the default policy) and exactly the given status codes
+func statusCodesRetryPolicy(statusCodes []int) hrhttp.CheckRetry {
+	codes := make(map[int]bool, len(statusCodes))
+	for _, code := range statusCodes {
+		codes[code] = true
+	}
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if err != nil || ctx.Err() != nil {
+			// the default policy handles context and transport errors
+			return hrhttp.DefaultRetryPolicy(ctx, resp, err)
+		}
+		return codes[resp.StatusCode], nil
+	}
+}
+
+// maxElapsedRetryPolicy gives up retrying once maxElapsed has passed since the request has started;
+// it relies on the requestState attached to the request context by RetryTransport
+func maxElapsedRetryPolicy(check hrhttp.CheckRetry, maxElapsed time.Duration) hrhttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		shouldRetry, checkErr := check(ctx, resp, err)
+		if rs := stateOf(ctx); shouldRetry && rs != nil && time.Since(rs.start) >= maxElapsed {
+			return giveUp(err)
+		}
+		return shouldRetry, checkErr
+	}
+}
+
+// contextMaxAttemptsRetryPolicy gives up retrying once the retries of the request have reached the MaxAttempts
+// override of its context (see ContextWithMaxAttempts); it relies on the requestState attached to the
+// request context by RetryTransport to count the retries, without which only an override of 0 applies
+func contextMaxAttemptsRetryPolicy(check hrhttp.CheckRetry) hrhttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		shouldRetry, checkErr := check(ctx, resp, err)
+		if n, ok := maxAttemptsOf(ctx); shouldRetry && ok {
+			if rs := stateOf(ctx); (rs != nil && rs.attempt >= n) || (rs == nil && n == 0) {
+				return giveUp(err)
+			}
+		}
+		return shouldRetry, checkErr
+	}
+}
+
+// budgetRetryPolicy gives up retrying once the retry budget of the client is exhausted; it relies on the
+// requestState attached to the request context by RetryTransport. A token is withdrawn only if a retry
+// is to be made, i.e. not after the last attempt allowed by retryMax (the MaxAttempts of the client)
+func budgetRetryPolicy(check hrhttp.CheckRetry, retryMax int) hrhttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		shouldRetry, checkErr := check(ctx, resp, err)
+		if rs := stateOf(ctx); shouldRetry && rs != nil && rs.budget != nil && rs.attempt < retryMax {
+			if !rs.budget.withdraw() {
+				return giveUp(err)
+			}
+		}
+		return shouldRetry, checkErr
+	}
+}
+
+// giveUp is returned by the retry policies which give up retrying a retryable attempt, whose error
+// is err (nil if it has a response): the retriesGivenUpError makes retryablehttp call the ErrorHandler,
+// which returns a RetriesExhaustedError, rather than return the response as a success
+func giveUp(err error) (bool, error) {
+	return false, &retriesGivenUpError{err: err}
+}
+
+// drainLimitRetryPolicy limits the bytes drained from the body of a response to be retried, by wrapping it
+// (retryablehttp drains it before retrying, to reuse the connection)
+func drainLimitRetryPolicy(check hrhttp.CheckRetry, limit int64) hrhttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		shouldRetry, checkErr := check(ctx, resp, err)
+		if shouldRetry && err == nil && resp != nil && resp.Body != nil {
+			resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: limit}
+		}
+		return shouldRetry, checkErr
+	}
+}
+
+// limitedBody reads up to remaining bytes, and drains up to the remaining bytes on Close
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (n int, err error) {
+	if b.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err = b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return
+}
+
+func (b *limitedBody) Close() error {
+	_, _ = io.Copy(io.Discard, io.LimitReader(b.ReadCloser, max(b.remaining, 0)))
+	return b.ReadCloser.Close()
+}
+
+// idempotentRetryPolicy does not retry requests which are not idempotent (see isIdempotent); the request
+// is taken from the requestState attached by RetryTransport, or else from the response. If neither is
+// available, i.e. a transport error of a request sent directly by the retryablehttp client, it is not retried
+func idempotentRetryPolicy(check hrhttp.CheckRetry) hrhttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		shouldRetry, checkErr := check(ctx, resp, err)
+		if shouldRetry {
+			if rs := stateOf(ctx); rs != nil {
+				shouldRetry = rs.idempotent
+			} else {
+				shouldRetry = resp != nil && resp.Request != nil && isIdempotent(resp.Request)
+			}
+		}
+		return shouldRetry, checkErr
+	}
+}
+
+// isIdempotent returns false for POST / PATCH requests, unless they carry an Idempotency-Key header
+func isIdempotent(req *http.Request) bool {
+	return (req.Method != http.MethodPost && req.Method != http.MethodPatch) || req.Header.Get(idempotencyKeyHeader) != common.Empty
+}
+
+func validStatusCodes(codes []int) (err error) {
+	for _, code := range codes {
+		if code < minStatusCode || code > maxStatusCode {
+			err = fmt.Errorf("invalid HTTP status code %d", code)
+			break
+		}
+	}
+	return
+}
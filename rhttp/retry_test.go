@@ -0,0 +1,148 @@
+package rhttp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrainLimitConnectionReuse(t *testing.T) {
+	tests := []struct {
+		name          string
+		bodySize      int
+		maxDrainBytes int
+		wantReused    bool
+	}{
+		{"body within drain limit", 64 << 10, 128 << 10, true},
+		{"body beyond drain limit", 1 << 20, 1 << 10, false},
+		{"body beyond default drain limit", 1 << 20, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.Repeat([]byte("x"), tt.bodySize)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write(body)
+			}))
+			defer srv.Close()
+			rc := &RetryConfig{
+				WaitMin:       time.Millisecond,
+				WaitMax:       time.Millisecond,
+				MaxAttempts:   3,
+				MaxDrainBytes: tt.maxDrainBytes,
+			}
+			c, err := rc.NewClient(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var attempts, reused atomic.Int32
+			trace := &httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					attempts.Add(1)
+					if info.Reused {
+						reused.Add(1)
+					}
+				},
+			}
+			req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp, err := c.Do(req); err == nil {
+				_ = resp.Body.Close()
+			}
+			if attempts.Load() != int32(rc.MaxAttempts+1) {
+				t.Fatalf("attempts = %d, want %d", attempts.Load(), rc.MaxAttempts+1)
+			}
+			// each retry reuses the connection of the previous attempt if its body has been drained
+			wantReused := int32(0)
+			if tt.wantReused {
+				wantReused = int32(rc.MaxAttempts)
+			}
+			if reused.Load() != wantReused {
+				t.Errorf("reused connections = %d, want %d", reused.Load(), wantReused)
+			}
+		})
+	}
+}
+
+func TestMaxElapsedCapsTheWait(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	rc := &RetryConfig{
+		WaitMin:     time.Second,
+		WaitMax:     time.Second,
+		MaxAttempts: 5,
+		Policy:      ConstantPolicy,
+		MaxElapsed:  100 * time.Millisecond,
+	}
+	c, err := rc.NewClient(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if resp, err := c.Get(srv.URL); err == nil {
+		_ = resp.Body.Close()
+	}
+	// the wait of one second is capped at the 100ms left, after which no retry is made
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Errorf("request took %v, want about %v", elapsed, rc.MaxElapsed)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+}
+
+func TestIdempotentOnly(t *testing.T) {
+	tests := []struct {
+		method         string
+		idempotencyKey string
+		wantCalls      int32
+	}{
+		{http.MethodGet, "", 2},
+		{http.MethodHead, "", 2},
+		{http.MethodPut, "", 2},
+		{http.MethodDelete, "", 2},
+		{http.MethodPost, "", 1},
+		{http.MethodPatch, "", 1},
+		{http.MethodPost, "4f2a", 2},
+		{http.MethodPatch, "4f2a", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.idempotencyKey, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+			rc := &RetryConfig{WaitMin: time.Millisecond, WaitMax: time.Millisecond, MaxAttempts: 1, IdempotentOnly: true}
+			c, err := rc.NewClient(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(tt.method, srv.URL, bytes.NewReader([]byte("body")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.idempotencyKey != "" {
+				req.Header.Set(idempotencyKeyHeader, tt.idempotencyKey)
+			}
+			if resp, err := c.Do(req); err == nil {
+				_ = resp.Body.Close()
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
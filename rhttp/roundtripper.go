@@ -0,0 +1,78 @@
+package rhttp
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// requestState is the state of a request across all its attempts, carried by the request context
+type requestState struct {
+	start      time.Time
+	idempotent bool
+	// the current attempt, recorded by the RequestLogHook
+	method, host string
+	attempt      int
+	attemptStart time.Time
+	// the previous wait of the decorrelated jitter policy
+	prevWait time.Duration
+	// the retry budget of the client, if any
+	budget *retryBudget
+}
+
+type requestStateKey struct{}
+
+func stateOf(ctx context.Context) *requestState {
+	rs, _ := ctx.Value(requestStateKey{}).(*requestState)
+	return rs
+}
+
+// RetryTransport is the http.RoundTripper of the clients returned by NewClient, i.e. client.Transport;
+// it wraps the retrying RoundTripper, attaching a new requestState to each request, and it is
+// safe for concurrent use
+type RetryTransport struct {
+	rt        http.RoundTripper
+	coalescer *coalescer
+	budget    *retryBudget
+	breaker   *circuitBreaker
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.coalescer != nil && coalescable(req) {
+		return t.coalescer.roundTrip(t.roundTrip, req)
+	}
+	return t.roundTrip(req)
+}
+
+// roundTrip sends req, once for identical requests if they are coalesced, hence the circuit breaker
+// and the retry budget count it once
+func (t *RetryTransport) roundTrip(req *http.Request) (resp *http.Response, err error) {
+	if t.breaker != nil {
+		if err = t.breaker.allow(); err != nil {
+			return
+		}
+		defer func() { t.breaker.record(resp, err) }()
+	}
+	rs := &requestState{start: time.Now(), idempotent: isIdempotent(req), budget: t.budget}
+	if t.budget != nil {
+		t.budget.deposit()
+	}
+	return t.rt.RoundTrip(req.WithContext(context.WithValue(req.Context(), requestStateKey{}, rs)))
+}
+
+// CircuitState returns the current state of the circuit breaker, ok is false if there is none
+// (see RetryConfig.CircuitBreaker)
+func (t *RetryTransport) CircuitState() (cs CircuitState, ok bool) {
+	if ok = t.breaker != nil; ok {
+		cs = t.breaker.currentState()
+	}
+	return
+}
+
+// Budget returns the current state of the retry budget, ok is false if there is none (see RetryConfig.Budget)
+func (t *RetryTransport) Budget() (bs BudgetState, ok bool) {
+	if ok = t.budget != nil; ok {
+		bs = t.budget.state()
+	}
+	return
+}
@@ -0,0 +1,31 @@
+package rhttp
+
+import (
+	"fmt"
+	hrhttp "github.com/hashicorp/go-retryablehttp"
+	"log/slog"
+)
+
+// *slog.Logger satisfies the retryablehttp LeveledLogger interface as is
+var _ hrhttp.LeveledLogger = (*slog.Logger)(nil)
+
+// AdaptSlog returns l as a retryablehttp LeveledLogger (slog.Default() if l is nil),
+// mapping the Debug, Info, Warn and Error levels to the same slog levels
+func AdaptSlog(l *slog.Logger) hrhttp.LeveledLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return l
+}
+
+// adaptLogger validates the logger passed to NewClient, adapting a slog.Handler to a LeveledLogger
+func adaptLogger(logger interface{}) (interface{}, error) {
+	switch l := logger.(type) {
+	case nil, hrhttp.Logger, hrhttp.LeveledLogger:
+		return l, nil
+	case slog.Handler:
+		return AdaptSlog(slog.New(l)), nil
+	default:
+		return nil, fmt.Errorf("invalid logger type %T", logger)
+	}
+}
@@ -0,0 +1,70 @@
+package rhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/densify-dev/net-utils/common"
+	"net/http"
+	"os"
+)
+
+// TLSConfig is the TLS configuration of the transport of a client; all attributes are optional
+type TLSConfig struct {
+	// CAFile is a PEM file of the CA certificates used to verify servers, instead of the system pool
+	CAFile string `yaml:"ca_file,omitempty"`
+	// CertFile and KeyFile are PEM files of the client certificate and its key, which must be set together
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+}
+
+// Validate checks that the files of tc exist and can be parsed
+func (tc *TLSConfig) Validate() (err error) {
+	_, err = tc.NewTLSConfig()
+	return
+}
+
+// NewTLSConfig returns a *tls.Config per tc (nil if tc is nil)
+func (tc *TLSConfig) NewTLSConfig() (*tls.Config, error) {
+	if tc == nil {
+		return nil, nil
+	}
+	c := &tls.Config{
+		InsecureSkipVerify: tc.InsecureSkipVerify,
+		ServerName:         tc.ServerName,
+	}
+	if tc.CAFile != common.Empty {
+		pem, err := os.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates in CA file %s", tc.CAFile)
+		}
+	}
+	if (tc.CertFile == common.Empty) != (tc.KeyFile == common.Empty) {
+		return nil, fmt.Errorf("certificate file and key file must be set together")
+	}
+	if tc.CertFile != common.Empty {
+		cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// NewTransport returns a clone of http.DefaultTransport, using the *tls.Config per tc
+func (tc *TLSConfig) NewTransport() (*http.Transport, error) {
+	c, err := tc.NewTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = c
+	return t, nil
+}
@@ -0,0 +1,106 @@
+package rhttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/densify-dev/net-utils/common"
+	"golang.org/x/net/http/httpproxy"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// TransportConfig tunes the connection pooling of the transport of a client; all attributes
+// are optional, zero values keep the settings of http.DefaultTransport
+type TransportConfig struct {
+	MaxIdleConns        int           `yaml:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host,omitempty"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout,omitempty"`
+	DisableKeepAlives   bool          `yaml:"disable_keep_alives,omitempty"`
+}
+
+// Validate checks that tc has no negative values
+func (tc *TransportConfig) Validate() (err error) {
+	if tc != nil {
+		err = errors.Join(
+			validNonNegativeInt(tc.MaxIdleConns, "max idle connections"),
+			validNonNegativeInt(tc.MaxIdleConnsPerHost, "max idle connections per host"),
+			validNonNegative(tc.IdleConnTimeout, "idle connection timeout"),
+		)
+	}
+	return
+}
+
+// NewTransport returns a clone of http.DefaultTransport, tuned per tc
+func (tc *TransportConfig) NewTransport() (*http.Transport, error) {
+	if err := tc.Validate(); err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if tc != nil {
+		if tc.MaxIdleConns > 0 {
+			t.MaxIdleConns = tc.MaxIdleConns
+		}
+		if tc.MaxIdleConnsPerHost > 0 {
+			t.MaxIdleConnsPerHost = tc.MaxIdleConnsPerHost
+		}
+		if tc.IdleConnTimeout > 0 {
+			t.IdleConnTimeout = tc.IdleConnTimeout
+		}
+		t.DisableKeepAlives = tc.DisableKeepAlives
+	}
+	return t, nil
+}
+
+// NewTransport returns the transport used by NewClient if it is passed a nil RoundTripper:
+// a clone of http.DefaultTransport, per the transport, TLS and proxy configurations of rc
+func (rc *RetryConfig) NewTransport() (t *http.Transport, err error) {
+	if rc == nil {
+		rc = &RetryConfig{}
+	}
+	var tlsConfig *tls.Config
+	if tlsConfig, err = rc.TLS.NewTLSConfig(); err == nil {
+		if t, err = rc.Transport.NewTransport(); err == nil {
+			t.TLSClientConfig = tlsConfig
+			t.Proxy = rc.proxy()
+		}
+	}
+	return
+}
+
+// proxy returns the proxy function of the transport: if ProxyURL is empty, the proxy
+// is taken from the environment (see http.ProxyFromEnvironment); otherwise, ProxyURL is used
+// for all requests, except those whose host matches NoProxy (or, if empty, NO_PROXY)
+func (rc *RetryConfig) proxy() func(*http.Request) (*url.URL, error) {
+	if rc.ProxyURL == common.Empty {
+		return http.ProxyFromEnvironment
+	}
+	noProxy := rc.NoProxy
+	if noProxy == common.Empty {
+		noProxy = httpproxy.FromEnvironment().NoProxy
+	}
+	pc := &httpproxy.Config{
+		HTTPProxy:  rc.ProxyURL,
+		HTTPSProxy: rc.ProxyURL,
+		NoProxy:    noProxy,
+	}
+	proxyFunc := pc.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+func validProxyURL(s string) (err error) {
+	if s == common.Empty {
+		return
+	}
+	var u *url.URL
+	if u, err = url.Parse(s); err == nil && (!slices.Contains(proxySchemes, u.Scheme) || u.Host == common.Empty) {
+		err = fmt.Errorf("invalid proxy URL %s: scheme must be one of %v, and host must be set", s, proxySchemes)
+	}
+	return
+}
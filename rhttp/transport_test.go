@@ -0,0 +1,109 @@
+package rhttp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "env.example.com")
+	tests := []struct {
+		name    string
+		noProxy string
+		url     string
+		want    string
+	}{
+		{"http", "", "http://api.example.com/v1", "http://proxy.internal:3128"},
+		{"https", "", "https://api.example.com/v1", "http://proxy.internal:3128"},
+		{"NO_PROXY", "", "https://env.example.com", ""},
+		{"no proxy", "internal.example.com,.svc", "https://internal.example.com", ""},
+		{"no proxy domain", "internal.example.com,.svc", "http://api.ns.svc", ""},
+		{"not no proxy", "internal.example.com,.svc", "https://api.example.com", "http://proxy.internal:3128"},
+		// NoProxy replaces NO_PROXY rather than extending it
+		{"NO_PROXY overridden", "internal.example.com", "https://env.example.com", "http://proxy.internal:3128"},
+		// as per NO_PROXY, requests to localhost are never proxied
+		{"localhost", "", "http://localhost:8080", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &RetryConfig{ProxyURL: "http://proxy.internal:3128", NoProxy: tt.noProxy}
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := rc.proxy()(req)
+			if err != nil {
+				t.Fatalf("proxy(%s) = %v", tt.url, err)
+			}
+			got := ""
+			if u != nil {
+				got = u.String()
+			}
+			if got != tt.want {
+				t.Errorf("proxy(%s) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidProxyURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"", true},
+		{"http://proxy:3128", true},
+		{"https://proxy", true},
+		{"socks5://127.0.0.1:1080", true},
+		{"socks5h://proxy:1080", true},
+		{"ftp://proxy:21", false},
+		{"proxy:3128", false},
+		{"http://", false},
+		{"http://proxy:3128/%zz", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := validProxyURL(tt.url); (err == nil) != tt.valid {
+				t.Errorf("validProxyURL(%q) = %v, valid %v", tt.url, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestTransportConfigNewTransport(t *testing.T) {
+	dt := http.DefaultTransport.(*http.Transport)
+	tests := []struct {
+		name                string
+		tc                  *TransportConfig
+		maxIdleConns        int
+		maxIdleConnsPerHost int
+		idleConnTimeout     time.Duration
+		valid               bool
+	}{
+		{"nil", nil, dt.MaxIdleConns, dt.MaxIdleConnsPerHost, dt.IdleConnTimeout, true},
+		{"zero", &TransportConfig{}, dt.MaxIdleConns, dt.MaxIdleConnsPerHost, dt.IdleConnTimeout, true},
+		{"tuned", &TransportConfig{MaxIdleConns: 50, MaxIdleConnsPerHost: 5, IdleConnTimeout: time.Minute}, 50, 5, time.Minute, true},
+		{"negative max idle", &TransportConfig{MaxIdleConns: -1}, 0, 0, 0, false},
+		{"negative max idle per host", &TransportConfig{MaxIdleConnsPerHost: -1}, 0, 0, 0, false},
+		{"negative idle timeout", &TransportConfig{IdleConnTimeout: -time.Second}, 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := tt.tc.NewTransport()
+			if (err == nil) != tt.valid {
+				t.Fatalf("NewTransport() = %v, valid %v", err, tt.valid)
+			}
+			if !tt.valid {
+				return
+			}
+			if tr == dt {
+				t.Fatal("NewTransport() = http.DefaultTransport, want a clone")
+			}
+			if tr.MaxIdleConns != tt.maxIdleConns || tr.MaxIdleConnsPerHost != tt.maxIdleConnsPerHost || tr.IdleConnTimeout != tt.idleConnTimeout {
+				t.Errorf("NewTransport() = %d, %d, %v, want %d, %d, %v", tr.MaxIdleConns, tr.MaxIdleConnsPerHost,
+					tr.IdleConnTimeout, tt.maxIdleConns, tt.maxIdleConnsPerHost, tt.idleConnTimeout)
+			}
+		})
+	}
+}